@@ -1,8 +1,10 @@
 package webostv
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/mitchellh/mapstructure"
 	"github.com/pkg/errors"
@@ -22,17 +24,53 @@ var (
 )
 
 type Tv struct {
-	Address      string
-	ws           *websocket.Conn
-	wsWriteMutex sync.Mutex
-	respCh       map[string]chan<- Msg
-	respChMutex  sync.Mutex
-	debugFunc    func(string)
+	Address       string
+	dialer        *Dialer
+	ws            *websocket.Conn
+	closed        bool
+	done          chan struct{}   // closed by Close
+	fingerprint   string          // fingerprint of the TLS certificate of the TV
+	registerOpts  RegisterOptions // options of the last successful registration
+	pointer       *PointerSocket  // managed pointer socket, see Pointer
+	caps          *Capabilities   // see DetectCapabilities
+	wsMutex       sync.Mutex      // protects ws, closed, fingerprint, registerOpts, pointer and caps
+	wsWriteMutex  sync.Mutex
+	respCh        map[string]chan<- Msg
+	subs          map[string]*subscription
+	respChMutex   sync.Mutex // protects respCh and subs
+	debugFunc     func(string)
+	logger        Logger
+	unhandledFunc func(Msg)
+	hooks         Hooks
+	recorder      *Recorder
+	events        eventHandlers
+}
+
+// subscription records an active subscription so that it can be
+// re-established after reconnecting.
+type subscription struct {
+	uri   string
+	req   Payload
+	queue *subQueue
 }
 
 type Dialer struct {
 	DisableTLS      bool
 	WebsocketDialer *websocket.Dialer
+
+	// PinnedFingerprint returns the certificate fingerprint pinned for
+	// the TV at address, see Tv.Fingerprint. If it returns an empty
+	// string or is nil, any certificate is trusted. Otherwise a
+	// CertificateMismatchError is returned if the TV presents a
+	// different certificate. Alternatively the fingerprint can be kept
+	// in KeyStore.
+	PinnedFingerprint func(address string) string
+
+	// KeyStore keeps the client keys and certificate fingerprints of the
+	// TVs, keyed by TV UUID. If it is set, Register asks the UUID of the
+	// TV, verifies its certificate and uses the stored client key unless
+	// one is given, and stores the new credentials after registering.
+	KeyStore KeyStore
 }
 
 var DefaultDialer = Dialer{
@@ -51,6 +89,26 @@ var DefaultDialer = Dialer{
 }
 
 func (dialer *Dialer) Dial(address string) (tv *Tv, err error) {
+	return dialer.DialContext(context.Background(), address)
+}
+
+func (dialer *Dialer) DialContext(ctx context.Context, address string) (tv *Tv, err error) {
+	ws, fingerprint, err := dialer.dialTv(ctx, address, dialer.pinnedFingerprint(address))
+	if err != nil {
+		return nil, err
+	}
+	return &Tv{
+		Address:     address,
+		dialer:      dialer,
+		ws:          ws,
+		done:        make(chan struct{}),
+		fingerprint: fingerprint,
+	}, nil
+}
+
+// dialTv connects to the TV and verifies its certificate against the
+// expected fingerprint, see verifyFingerprint.
+func (dialer *Dialer) dialTv(ctx context.Context, address, expected string) (ws *websocket.Conn, fingerprint string, err error) {
 	var url string
 	if dialer.DisableTLS {
 		url = "ws://" + address + ":3000"
@@ -61,19 +119,21 @@ func (dialer *Dialer) Dial(address string) (tv *Tv, err error) {
 	if wsDialer == nil {
 		wsDialer = websocket.DefaultDialer
 	}
-	ws, resp, err := wsDialer.Dial(url, nil)
+	ws, resp, err := wsDialer.DialContext(ctx, url, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	err = resp.Body.Close()
 	if err != nil {
-		return nil, err
+		ws.Close()
+		return nil, "", err
 	}
-
-	return &Tv{
-		Address: address,
-		ws:      ws,
-	}, nil
+	fingerprint, err = verifyFingerprint(ws, address, expected)
+	if err != nil {
+		ws.Close()
+		return nil, "", err
+	}
+	return ws, fingerprint, nil
 }
 
 func (tv *Tv) debug(str string, buf []byte) {
@@ -90,144 +150,35 @@ func (tv *Tv) SetDebug(debugFunc func(string)) {
 	tv.debugFunc = debugFunc
 }
 
-func helloPayload() Payload {
-	return Payload{
-		"forcePairing": false,
-		"pairingType":  "PROMPT",
-		"manifest": map[string]interface{}{
-			"manifestVersion": 1,
-			"appVersion":      "1.1",
-			"signed": map[string]interface{}{
-				"created":  "20140509",
-				"appId":    "com.lge.test",
-				"vendorId": "com.lge",
-				"localizedAppNames": map[string]string{
-					"":       "LG Remote App",
-					"ko-KR":  "리모컨 앱",
-					"zxx-XX": "ЛГ Rэмotэ AПП",
-				},
-				"localizedVendorNames": map[string]string{
-					"": "LG Electronics",
-				},
-				"permissions": []string{
-					"TEST_SECURE",
-					"CONTROL_INPUT_TEXT",
-					"CONTROL_MOUSE_AND_KEYBOARD",
-					"READ_INSTALLED_APPS",
-					"READ_LGE_SDX",
-					"READ_NOTIFICATIONS",
-					"SEARCH",
-					"WRITE_SETTINGS",
-					"WRITE_NOTIFICATION_ALERT",
-					"CONTROL_POWER",
-					"READ_CURRENT_CHANNEL",
-					"READ_RUNNING_APPS",
-					"READ_UPDATE_INFO",
-					"UPDATE_FROM_REMOTE_APP",
-					"READ_LGE_TV_INPUT_EVENTS",
-					"READ_TV_CURRENT_TIME",
-				},
-				"serial": "2f930e2d2cfe083771f68e4fe7bb07",
-			},
-			"permissions": []string{
-				"LAUNCH",
-				"LAUNCH_WEBAPP",
-				"APP_TO_APP",
-				"CLOSE",
-				"TEST_OPEN",
-				"TEST_PROTECTED",
-				"CONTROL_AUDIO",
-				"CONTROL_DISPLAY",
-				"CONTROL_INPUT_JOYSTICK",
-				"CONTROL_INPUT_MEDIA_RECORDING",
-				"CONTROL_INPUT_MEDIA_PLAYBACK",
-				"CONTROL_INPUT_TV",
-				"CONTROL_POWER",
-				"READ_APP_STATUS",
-				"READ_CURRENT_CHANNEL",
-				"READ_INPUT_DEVICE_LIST",
-				"READ_NETWORK_STATE",
-				"READ_RUNNING_APPS",
-				"READ_TV_CHANNEL_LIST",
-				"WRITE_NOTIFICATION_TOAST",
-				"READ_POWER_STATE",
-				"READ_COUNTRY_INFO",
-			},
-			"signatures": []map[string]interface{}{
-				map[string]interface{}{
-					"signatureVersion": 1,
-					"signature":        "eyJhbGdvcml0aG0iOiJSU0EtU0hBMjU2Iiwia2V5SWQiOiJ0ZXN0LXNpZ25pbmctY2VydCIsInNpZ25hdHVyZVZlcnNpb24iOjF9.hrVRgjCwXVvE2OOSpDZ58hR+59aFNwYDyjQgKk3auukd7pcegmE2CzPCa0bJ0ZsRAcKkCTJrWo5iDzNhMBWRyaMOv5zWSrthlf7G128qvIlpMT0YNY+n/FaOHE73uLrS/g7swl3/qH/BGFG2Hu4RlL48eb3lLKqTt2xKHdCs6Cd4RMfJPYnzgvI4BNrFUKsjkcu+WD4OO2A27Pq1n50cMchmcaXadJhGrOqH5YmHdOCj5NSHzJYrsW0HPlpuAx/ECMeIZYDh6RMqaFM2DXzdKX9NmmyqzJ3o/0lkk/N97gfVRLW5hA29yeAwaCViZNCP8iC9aO0q9fQojoa7NQnAtw==",
-				},
-			},
-		},
-	}
+// SetUnhandledMessageFunc sets a function which is called with the
+// messages which do not belong to any pending request or active
+// subscription. Such messages are also delivered as events, see OnEvent
+// and Events. The function is called from the message handler goroutine,
+// so it must not block.
+func (tv *Tv) SetUnhandledMessageFunc(f func(Msg)) {
+	tv.unhandledFunc = f
 }
 
-func (tv *Tv) Register(key string) (newKey string, err error) {
-	helloMsg := Msg{
-		Type:    "register",
-		Id:      makeId(),
-		Payload: helloPayload(),
-	}
-	ch := make(chan Msg, 1)
-	tv.registerRespCh(helloMsg.Id, ch)
-	defer tv.unregisterRespCh(helloMsg.Id)
-
-	if key != "" {
-		helloMsg.Payload["client-key"] = key
-	}
-
-	err = tv.writeJSON(&helloMsg)
-	if err != nil {
-		return "", err
-	}
-
-	var respMsg Msg
-	var ok bool
-
-	for {
-		select {
-		case respMsg, ok = <-ch:
-			if !ok {
-				return "", ErrNoResponse
-			}
-			err = checkResponse(respMsg)
-			if err != nil {
-				return "", err
-			}
-
-		case <-time.After(RegisterTimeout):
-			return "", ErrTimeout
-		}
-		if respMsg.Type != "response" {
-			break
-		}
+func (tv *Tv) writeJSON(ctx context.Context, msg *Msg) error {
+	if ctx.Err() != nil {
+		return contextError(ctx)
 	}
-	if respMsg.Type != "registered" {
-		return "", ErrRegistrationFailed
-	}
-	if tmp, ok := respMsg.Payload["client-key"]; ok {
-		tmp, ok := tmp.(string)
-		if !ok {
-			return "", errors.New("client-key from TV is not a string")
-		}
-		newKey = tmp
-	}
-	return newKey, nil
-}
-
-func (tv *Tv) writeJSON(v interface{}) error {
-	buf, err := json.Marshal(v)
+	buf, err := json.Marshal(msg)
 	if err != nil {
 		return errors.Wrap(err, "JSON marshal error")
 	}
 	tv.debug("write: ", buf)
+	deadline, _ := ctx.Deadline() // zero value means no deadline
+	ws := tv.conn()
 	tv.wsWriteMutex.Lock()
-	err = tv.ws.WriteMessage(websocket.TextMessage, buf)
+	ws.SetWriteDeadline(deadline)
+	err = ws.WriteMessage(websocket.TextMessage, buf)
 	tv.wsWriteMutex.Unlock()
 	if err != nil {
 		return errors.Wrap(err, "websocket write error")
 	}
+	tv.record(FrameSent, websocket.TextMessage, buf)
+	tv.logMessage(FrameSent, msg)
 	return nil
 }
 
@@ -246,11 +197,32 @@ func (tv *Tv) unregisterRespCh(id string) {
 		close(ch)
 		delete(tv.respCh, id)
 	}
+	if sub, ok := tv.subs[id]; ok {
+		sub.queue.close()
+		delete(tv.subs, id)
+	}
 	tv.respChMutex.Unlock()
 }
 
+func (tv *Tv) conn() *websocket.Conn {
+	tv.wsMutex.Lock()
+	defer tv.wsMutex.Unlock()
+	return tv.ws
+}
+
 func (tv *Tv) Close() (err error) {
-	err = tv.ws.Close()
+	tv.wsMutex.Lock()
+	if !tv.closed {
+		tv.closed = true
+		close(tv.done)
+	}
+	ws := tv.ws
+	pointer := tv.pointer
+	tv.wsMutex.Unlock()
+	if pointer != nil {
+		pointer.Close()
+	}
+	err = ws.Close()
 	return err
 }
 
@@ -279,42 +251,94 @@ type Msg struct {
 }
 
 func (tv *Tv) MessageHandler() (err error) {
-	defer func() {
-		// close the channels to indicate that the reader is exiting
-		tv.respChMutex.Lock()
-		for _, ch := range tv.respCh {
-			close(ch)
-		}
-		tv.respCh = nil
-		tv.respChMutex.Unlock()
-	}()
+	// close the channels to indicate that the reader is exiting
+	defer tv.closeRespChs()
+
+	err = tv.readMessages(tv.conn())
+	tv.wsMutex.Lock()
+	closed := tv.closed
+	tv.wsMutex.Unlock()
+	if !closed {
+		tv.hookDisconnect(err)
+	}
+	return err
+}
 
+func (tv *Tv) closeRespChs() {
+	tv.respChMutex.Lock()
+	for _, ch := range tv.respCh {
+		close(ch)
+	}
+	for _, sub := range tv.subs {
+		sub.queue.close()
+	}
+	tv.respCh = nil
+	tv.subs = nil
+	tv.respChMutex.Unlock()
+	tv.closeEvents()
+}
+
+func (tv *Tv) readMessages(ws *websocket.Conn) (err error) {
 	for {
-		messageType, p, err := tv.ws.ReadMessage()
+		messageType, p, err := ws.ReadMessage()
 		if err != nil {
 			return err
 		}
 		tv.debug("read: ", p)
+		tv.record(FrameReceived, messageType, p)
 		if messageType != websocket.TextMessage {
-			tv.debug("non-text message type, ignored", nil)
+			tv.logWarn("non-text message type, ignored")
 			continue
 		}
 		var msg Msg
 		err = json.Unmarshal(p, &msg)
 		if err != nil {
-			tv.debug("invalid json in message, ignored", nil)
+			tv.logWarn("invalid json in message, ignored", "error", err)
 			continue
 		}
-		tv.respChMutex.Lock()
-		ch := tv.respCh[msg.Id]
-		tv.respChMutex.Unlock()
-		ch <- msg
+		tv.logMessage(FrameReceived, &msg)
+		tv.dispatch(msg)
 	}
 	// not reached
 }
 
+// dispatch passes msg to the request or subscription it belongs to. It
+// does not block unless the subscription uses OverflowBlock.
+func (tv *Tv) dispatch(msg Msg) {
+	tv.respChMutex.Lock()
+	if ch, ok := tv.respCh[msg.Id]; ok {
+		// the channels of requests have room for the expected responses
+		select {
+		case ch <- msg:
+		default:
+			tv.logWarn("response channel full, message dropped", "id", msg.Id)
+		}
+		tv.respChMutex.Unlock()
+		return
+	}
+	sub := tv.subs[msg.Id]
+	tv.respChMutex.Unlock()
+
+	if sub != nil {
+		ok := sub.queue.push(msg)
+		if !ok {
+			tv.logWarn("subscription queue full, message dropped", "uri", sub.uri)
+		}
+		tv.hookSubscriptionMessage(sub.uri, !ok)
+		return
+	}
+	if tv.unhandledFunc != nil {
+		tv.unhandledFunc(msg)
+	}
+	tv.emitEvent(msg)
+}
+
 func (tv *Tv) RequestResponseParam(uri string, req Payload, resp interface{}) (err error) {
-	r, err := tv.Request(uri, req)
+	return tv.RequestResponseParamContext(context.Background(), uri, req, resp)
+}
+
+func (tv *Tv) RequestResponseParamContext(ctx context.Context, uri string, req Payload, resp interface{}) (err error) {
+	r, err := tv.RequestContext(ctx, uri, req)
 	if err != nil {
 		return err
 	}
@@ -322,17 +346,32 @@ func (tv *Tv) RequestResponseParam(uri string, req Payload, resp interface{}) (e
 }
 
 func (tv *Tv) Request(uri string, req Payload) (resp Payload, err error) {
+	return tv.RequestContext(context.Background(), uri, req)
+}
+
+// RequestContext is like Request but honours ctx. Timeout is applied if
+// ctx has no deadline.
+func (tv *Tv) RequestContext(ctx context.Context, uri string, req Payload) (resp Payload, err error) {
+	ctx, cancel := withDefaultTimeout(ctx, Timeout)
+	defer cancel()
+
 	var msg Msg
 	msg.Type = "request"
 	msg.Id = makeId()
 	msg.Uri = uri
 	msg.Payload = req
 
+	start := time.Now()
+	defer func() {
+		tv.hookRequest(uri, start, err)
+		tv.logRequest(msg.Id, uri, start, err)
+	}()
+
 	ch := make(chan Msg, 1)
 	tv.registerRespCh(msg.Id, ch)
 	defer tv.unregisterRespCh(msg.Id)
 
-	err = tv.writeJSON(&msg)
+	err = tv.writeJSON(ctx, &msg)
 	if err != nil {
 		return nil, err
 	}
@@ -341,26 +380,63 @@ func (tv *Tv) Request(uri string, req Payload) (resp Payload, err error) {
 		if !ok {
 			return nil, ErrNoResponse
 		}
-		err = checkResponse(respMsg)
+		err = checkResponse(uri, respMsg)
 		return respMsg.Payload, err
-	case <-time.After(Timeout):
-		return nil, ErrTimeout
+	case <-ctx.Done():
+		return nil, contextError(ctx)
+	}
+}
+
+// withDefaultTimeout returns a context derived from ctx which has the given
+// timeout unless ctx already has a deadline of its own.
+func withDefaultTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, timeout)
 }
 
-func checkResponse(r Msg) (err error) {
+// contextError maps the error of a done context to the errors returned by
+// this package. Expired deadlines are reported as ErrTimeout.
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return ctx.Err()
+}
+
+// quitContext returns a context which is canceled when quit is closed.
+// It is used to implement the quit channel based functions on top of
+// their context based variants.
+func quitContext(quit <-chan struct{}) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+func checkResponse(uri string, r Msg) (err error) {
 	switch r.Type {
 	case "error":
-		var err2 error
-		if _, ok := r.Payload["returnValue"]; ok {
-			err2 = checkPayloadReturnValue(r.Payload)
+		apiErr := &APIError{
+			MsgType: r.Type,
+			Uri:     uri,
+			Message: r.Error,
 		}
-		if err2 == nil {
-			return errors.Errorf("API error: %s", r.Error)
+		if _, ok := r.Payload["returnValue"]; ok {
+			if err2, ok := checkPayloadReturnValue(uri, r.Payload).(*APIError); ok {
+				apiErr.ErrorCode = err2.ErrorCode
+				apiErr.ErrorText = err2.ErrorText
+			}
 		}
-		return errors.Errorf("API error: %s - %s", r.Error, err2)
+		return apiErr
 	case "response":
-		return checkPayloadReturnValue(r.Payload)
+		return checkPayloadReturnValue(uri, r.Payload)
 	case "registered":
 		if r.Payload == nil {
 			return errors.New("nil payload")
@@ -371,7 +447,7 @@ func checkResponse(r Msg) (err error) {
 	}
 }
 
-func checkPayloadReturnValue(p Payload) (err error) {
+func checkPayloadReturnValue(uri string, p Payload) (err error) {
 	if p == nil {
 		return errors.New("nil payload")
 	}
@@ -385,25 +461,54 @@ func checkPayloadReturnValue(p Payload) (err error) {
 		return errors.New("returnValue type is not bool")
 	}
 	if !returnValue {
+		apiErr := &APIError{
+			MsgType: "response",
+			Uri:     uri,
+		}
 		if p["errorCode"] != nil {
-			return errors.Errorf("error %v: %v", p["errorCode"], p["errorText"])
-		} else {
-			return errors.New("returnValue: false, errorCode: nil")
+			apiErr.ErrorCode = fmt.Sprint(p["errorCode"])
+		}
+		if p["errorText"] != nil {
+			apiErr.ErrorText = fmt.Sprint(p["errorText"])
 		}
+		return apiErr
 	}
 	return nil
 }
 
 func (tv *Tv) Subscribe(uri string, req Payload, msgCh chan<- Msg) (id string, err error) {
+	return tv.SubscribeContext(context.Background(), uri, req, msgCh)
+}
+
+// SubscribeContext is like Subscribe but honours ctx while sending the
+// subscription request. The subscription itself stays active until
+// Unsubscribe is called.
+func (tv *Tv) SubscribeContext(ctx context.Context, uri string, req Payload, msgCh chan<- Msg) (id string, err error) {
+	return tv.SubscribeWithOptions(ctx, uri, req, msgCh, DefaultSubscribeOptions)
+}
+
+// SubscribeWithOptions is like SubscribeContext but the queueing of the
+// messages to msgCh is controlled by opts. msgCh is closed when the
+// subscription ends.
+func (tv *Tv) SubscribeWithOptions(ctx context.Context, uri string, req Payload, msgCh chan<- Msg, opts SubscribeOptions) (id string, err error) {
 	var msg Msg
 	msg.Type = "subscribe"
 	msg.Id = makeId()
 	msg.Uri = uri
 	msg.Payload = req
 
-	tv.registerRespCh(msg.Id, msgCh)
+	tv.respChMutex.Lock()
+	if tv.subs == nil {
+		tv.subs = make(map[string]*subscription)
+	}
+	tv.subs[msg.Id] = &subscription{
+		uri:   uri,
+		req:   req,
+		queue: newSubQueue(msgCh, opts),
+	}
+	tv.respChMutex.Unlock()
 
-	err = tv.writeJSON(&msg)
+	err = tv.writeJSON(ctx, &msg)
 	if err != nil {
 		tv.unregisterRespCh(msg.Id)
 		return "", err
@@ -412,6 +517,10 @@ func (tv *Tv) Subscribe(uri string, req Payload, msgCh chan<- Msg) (id string, e
 }
 
 func (tv *Tv) Unsubscribe(uri string, id string, req Payload) error {
+	return tv.UnsubscribeContext(context.Background(), uri, id, req)
+}
+
+func (tv *Tv) UnsubscribeContext(ctx context.Context, uri string, id string, req Payload) error {
 	var msg Msg
 	msg.Type = "unsubscribe"
 	msg.Id = id
@@ -420,13 +529,22 @@ func (tv *Tv) Unsubscribe(uri string, id string, req Payload) error {
 
 	tv.unregisterRespCh(msg.Id)
 
-	return tv.writeJSON(&msg)
+	return tv.writeJSON(ctx, &msg)
 }
 
 func (tv *Tv) MonitorStatus(uri string, req Payload, processPayload func(Payload) error, quit <-chan struct{}) (err error) {
+	ctx, cancel := quitContext(quit)
+	defer cancel()
+	return tv.MonitorStatusContext(ctx, uri, req, processPayload)
+}
+
+// MonitorStatusContext is like MonitorStatus but the monitoring is stopped
+// when ctx is done. Like with MonitorStatus, nil is returned in that case.
+// An APIError is returned if the TV refuses the subscription.
+func (tv *Tv) MonitorStatusContext(ctx context.Context, uri string, req Payload, processPayload func(Payload) error) (err error) {
 	msgCh := make(chan Msg, 1)
 
-	id, err := tv.Subscribe(uri, req, msgCh)
+	id, err := tv.SubscribeContext(ctx, uri, req, msgCh)
 	if err != nil {
 		return err
 	}
@@ -438,6 +556,10 @@ func (tv *Tv) MonitorStatus(uri string, req Payload, processPayload func(Payload
 			if !ok {
 				return nil
 			}
+			if msg.Type == "error" {
+				// the TV refused the subscription
+				return checkResponse(uri, msg)
+			}
 			if msg.Payload == nil {
 				continue
 			}
@@ -445,7 +567,7 @@ func (tv *Tv) MonitorStatus(uri string, req Payload, processPayload func(Payload
 			if err != nil {
 				return err
 			}
-		case <-quit:
+		case <-ctx.Done():
 			return nil
 		}
 	}
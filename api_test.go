@@ -0,0 +1,253 @@
+package webostv_test
+
+import (
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"testing"
+	"time"
+)
+
+func dialTestTv(t *testing.T, s *webostvtest.Server) *webostv.Tv {
+	t.Helper()
+	return dialTestTvWith(t, s.Dialer(), s)
+}
+
+func dialTestTvWith(t *testing.T, dialer *webostv.Dialer, s *webostvtest.Server) *webostv.Tv {
+	t.Helper()
+	tv, err := dialer.Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go tv.MessageHandler()
+	return tv
+}
+
+func registerTestTv(t *testing.T, s *webostvtest.Server) *webostv.Tv {
+	t.Helper()
+	tv := dialTestTv(t, s)
+	_, err := tv.Register("")
+	if err != nil {
+		tv.Close()
+		t.Fatal(err)
+	}
+	return tv
+}
+
+func TestRequest(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	var got webostv.Payload
+	s.Handle("ssap://audio/setVolume", func(req webostv.Payload) (webostv.Payload, error) {
+		got = req
+		return nil, nil
+	})
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	err := tv.AudioSetVolume(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := got["volume"].(float64); !ok || v != 12 {
+		t.Errorf("got volume %v, expected 12", got["volume"])
+	}
+}
+
+func TestRequestNotFound(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	_, err := tv.Request("ssap://no/such", nil)
+	if !webostv.IsNotFound(err) {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+	if apiErr, ok := err.(*webostv.APIError); !ok || apiErr.Uri != "ssap://no/such" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+}
+
+func TestRequestReturnValueFalse(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse("ssap://tv/openChannel", webostv.Payload{
+		"returnValue": false,
+		"errorCode":   -1000,
+		"errorText":   "invalid channel",
+	})
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	err := tv.TvOpenChannelNumber("999")
+	apiErr, ok := err.(*webostv.APIError)
+	if !ok {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.ErrorCode != "-1000" || apiErr.ErrorText != "invalid channel" || apiErr.Uri != "ssap://tv/openChannel" {
+		t.Errorf("unexpected error: %#v", apiErr)
+	}
+}
+
+func TestRequestNotRegistered(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse("ssap://audio/getMute", webostv.Payload{"mute": true})
+
+	tv := dialTestTv(t, s)
+	defer tv.Close()
+
+	_, err := tv.AudioGetMute()
+	if !webostv.IsPermissionDenied(err) {
+		t.Fatalf("expected permission denied error, got %v", err)
+	}
+}
+
+func TestTypedWrappers(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse("ssap://audio/getStatus", webostv.Payload{
+		"scenario": "mastervolume_tv_speaker",
+		"volume":   9,
+		"mute":     false,
+	})
+	s.HandleResponse("ssap://tv/getCurrentChannel", webostv.Payload{
+		"channelId":     "3_32_24_24_31_13105_0",
+		"channelNumber": "24",
+		"channelName":   "Nelonen HD",
+	})
+	s.HandleResponse("ssap://system/getSystemInfo", webostv.Payload{
+		"features":     webostv.Payload{"3d": true, "dvr": false},
+		"receiverType": "dvb",
+		"modelName":    "42LB650V-ZN",
+	})
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	as, err := tv.AudioGetStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if as.Volume != 9 || as.Mute || as.Scenario != "mastervolume_tv_speaker" {
+		t.Errorf("unexpected audio status: %+v", as)
+	}
+
+	cur, err := tv.TvGetCurrentChannel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cur.ChannelNumber != "24" || cur.ChannelName != "Nelonen HD" {
+		t.Errorf("unexpected current channel: %+v", cur)
+	}
+
+	info, err := tv.SystemGetSystemInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ModelName != "42LB650V-ZN" || !info.Features["3d"] || info.Features["dvr"] {
+		t.Errorf("unexpected system info: %+v", info)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse("ssap://audio/getStatus", webostv.Payload{"volume": 1})
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	ch := make(chan webostv.Msg, 10)
+	id, err := tv.Subscribe("ssap://audio/getStatus", nil, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := <-ch
+	if msg.Id != id || msg.Payload["volume"] != float64(1) {
+		t.Errorf("unexpected initial message: %+v", msg)
+	}
+
+	if n := s.Publish("ssap://audio/getStatus", webostv.Payload{"volume": 2}); n != 1 {
+		t.Fatalf("published to %d subscribers, expected 1", n)
+	}
+	msg = <-ch
+	if msg.Payload["volume"] != float64(2) {
+		t.Errorf("unexpected pushed message: %+v", msg)
+	}
+
+	err = tv.Unsubscribe("ssap://audio/getStatus", id, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("channel not closed after Unsubscribe")
+	}
+}
+
+func TestMonitorStatus(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse("ssap://audio/getStatus", webostv.Payload{"volume": 1})
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	volumes := make(chan int)
+	quit := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- tv.AudioMonitorStatus(func(as webostv.AudioStatus) error {
+			volumes <- as.Volume
+			return nil
+		}, quit)
+	}()
+
+	if v := <-volumes; v != 1 {
+		t.Errorf("got volume %d, expected 1", v)
+	}
+	s.Publish("ssap://audio/getStatus", webostv.Payload{"volume": 5})
+	if v := <-volumes; v != 5 {
+		t.Errorf("got volume %d, expected 5", v)
+	}
+
+	close(quit)
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("AudioMonitorStatus did not return after quit")
+	}
+}
+
+func TestUnhandledMessage(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tv.Close()
+	unhandled := make(chan webostv.Msg, 1)
+	tv.SetUnhandledMessageFunc(func(msg webostv.Msg) {
+		unhandled <- msg
+	})
+	go tv.MessageHandler()
+
+	s.Send(webostv.Msg{Type: "hello", Id: "unknown"})
+	select {
+	case msg := <-unhandled:
+		if msg.Type != "hello" {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("unhandled message not received")
+	}
+}
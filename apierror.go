@@ -0,0 +1,74 @@
+package webostv
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+)
+
+// APIError is returned when the TV responds to a request with an error
+// message or with "returnValue": false.
+type APIError struct {
+	MsgType   string // type of the response message: "error" or "response"
+	Uri       string // URI of the request, empty for registration
+	Message   string // "error" of an error message, e.g. "404 no such service or method"
+	ErrorCode string // "errorCode" of the payload, if any
+	ErrorText string // "errorText" of the payload, if any
+}
+
+func (e *APIError) Error() string {
+	var code string
+	if e.ErrorCode != "" {
+		code = "error " + e.ErrorCode + ": " + e.ErrorText
+	} else {
+		code = "returnValue: false, errorCode: nil"
+	}
+	if e.MsgType != "error" {
+		return code
+	}
+	if e.ErrorCode == "" && e.ErrorText == "" {
+		return "API error: " + e.Message
+	}
+	return "API error: " + e.Message + " - " + code
+}
+
+// StatusCode returns the numeric status at the beginning of Message, for
+// example 404 for "404 no such service or method". Zero is returned if
+// there is none.
+func (e *APIError) StatusCode() int {
+	i := strings.IndexByte(e.Message, ' ')
+	if i < 0 {
+		i = len(e.Message)
+	}
+	code, err := strconv.Atoi(e.Message[:i])
+	if err != nil {
+		return 0
+	}
+	return code
+}
+
+func apiErrorStatusCode(err error) int {
+	if apiErr, ok := errors.Cause(err).(*APIError); ok {
+		return apiErr.StatusCode()
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is an APIError telling that the TV does
+// not have the requested service or method ("404 no such service or method").
+func IsNotFound(err error) bool {
+	return apiErrorStatusCode(err) == 404
+}
+
+// IsPermissionDenied reports whether err is an APIError telling that the
+// client was not granted the permission needed for the request
+// ("401 insufficient permissions").
+func IsPermissionDenied(err error) bool {
+	return apiErrorStatusCode(err) == 401
+}
+
+// IsPairingRejected reports whether err is an APIError telling that the
+// user rejected the pairing prompt on the TV ("403 User denied access").
+func IsPairingRejected(err error) bool {
+	return apiErrorStatusCode(err) == 403
+}
@@ -0,0 +1,110 @@
+package webostv
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// Button is the name of a remote control button which can be sent to the
+// TV with PointerSocket.Press.
+type Button string
+
+const (
+	ButtonUp          Button = "UP"
+	ButtonDown        Button = "DOWN"
+	ButtonLeft        Button = "LEFT"
+	ButtonRight       Button = "RIGHT"
+	ButtonEnter       Button = "ENTER"
+	ButtonBack        Button = "BACK"
+	ButtonExit        Button = "EXIT"
+	ButtonHome        Button = "HOME"
+	ButtonMenu        Button = "MENU"
+	ButtonQMenu       Button = "QMENU"
+	ButtonInfo        Button = "INFO"
+	ButtonGuide       Button = "GUIDE"
+	ButtonList        Button = "LIST"
+	ButtonRed         Button = "RED"
+	ButtonGreen       Button = "GREEN"
+	ButtonYellow      Button = "YELLOW"
+	ButtonBlue        Button = "BLUE"
+	ButtonChannelUp   Button = "CHANNELUP"
+	ButtonChannelDown Button = "CHANNELDOWN"
+	ButtonVolumeUp    Button = "VOLUMEUP"
+	ButtonVolumeDown  Button = "VOLUMEDOWN"
+	ButtonMute        Button = "MUTE"
+	ButtonPlay        Button = "PLAY"
+	ButtonPause       Button = "PAUSE"
+	ButtonStop        Button = "STOP"
+	ButtonRewind      Button = "REWIND"
+	ButtonFastForward Button = "FASTFORWARD"
+	ButtonRecord      Button = "RECORD"
+	ButtonAsterisk    Button = "ASTERISK"
+	ButtonDash        Button = "DASH"
+	ButtonCC          Button = "CC"
+	ButtonAD          Button = "AD"
+	ButtonSAP         Button = "SAP"
+	ButtonTeletext    Button = "TELETEXT"
+	Button3DMode      Button = "3D_MODE"
+	Button0           Button = "0"
+	Button1           Button = "1"
+	Button2           Button = "2"
+	Button3           Button = "3"
+	Button4           Button = "4"
+	Button5           Button = "5"
+	Button6           Button = "6"
+	Button7           Button = "7"
+	Button8           Button = "8"
+	Button9           Button = "9"
+)
+
+// Buttons lists all known buttons.
+var Buttons = []Button{
+	ButtonUp, ButtonDown, ButtonLeft, ButtonRight, ButtonEnter, ButtonBack,
+	ButtonExit, ButtonHome, ButtonMenu, ButtonQMenu, ButtonInfo, ButtonGuide,
+	ButtonList, ButtonRed, ButtonGreen, ButtonYellow, ButtonBlue,
+	ButtonChannelUp, ButtonChannelDown, ButtonVolumeUp, ButtonVolumeDown,
+	ButtonMute, ButtonPlay, ButtonPause, ButtonStop, ButtonRewind,
+	ButtonFastForward, ButtonRecord, ButtonAsterisk, ButtonDash, ButtonCC,
+	ButtonAD, ButtonSAP, ButtonTeletext, Button3DMode,
+	Button0, Button1, Button2, Button3, Button4,
+	Button5, Button6, Button7, Button8, Button9,
+}
+
+var ErrInvalidButton = errors.New("invalid button")
+
+var validButtons = func() map[Button]bool {
+	m := make(map[Button]bool)
+	for _, b := range Buttons {
+		m[b] = true
+	}
+	return m
+}()
+
+func (b Button) Valid() bool {
+	return validButtons[b]
+}
+
+// ParseButton returns the Button with the given name. The name is case
+// insensitive.
+func ParseButton(name string) (b Button, err error) {
+	b = Button(strings.ToUpper(strings.TrimSpace(name)))
+	if !b.Valid() {
+		return "", errors.Wrap(ErrInvalidButton, name)
+	}
+	return b, nil
+}
+
+// Press sends a button press. ErrInvalidButton is returned for unknown
+// buttons because the TV silently ignores them.
+func (ps *PointerSocket) Press(b Button) (err error) {
+	return ps.PressContext(context.Background(), b)
+}
+
+// PressContext is like Press but honours ctx.
+func (ps *PointerSocket) PressContext(ctx context.Context, b Button) (err error) {
+	if !b.Valid() {
+		return errors.Wrap(ErrInvalidButton, string(b))
+	}
+	return ps.InputContext(ctx, "button", string(b))
+}
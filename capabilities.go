@@ -0,0 +1,120 @@
+package webostv
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// ErrUnsupported is returned by the wrapper methods when the Capabilities
+// of the TV tell that it does not support the endpoint. Use errors.Cause
+// to compare.
+var ErrUnsupported = errors.New("not supported by the TV")
+
+// IsUnsupported reports whether err is caused by ErrUnsupported.
+func IsUnsupported(err error) bool {
+	return errors.Cause(err) == ErrUnsupported
+}
+
+// Capabilities describes which services and features the TV has. See
+// Tv.DetectCapabilities.
+type Capabilities struct {
+	// Services maps the names of the services listed by the TV, such as
+	// "audio" and "tv", to their versions. Nil if unknown.
+	Services map[string]int
+	// Features are the features reported in SystemInfo, such as "3d"
+	// and "dvr". Nil if unknown.
+	Features map[string]bool
+	// ReceiverType is the type of the TV tuner, such as "dvb" or "atsc".
+	ReceiverType string
+}
+
+// requiredFeatures maps the URIs of the endpoints which need a feature to
+// the name of the feature.
+var requiredFeatures = map[string]string{
+	UriGet3DStatus: "3d",
+	UriSet3DOff:    "3d",
+	UriSet3DOn:     "3d",
+}
+
+// service returns the name of the service of uri, for example "audio"
+// for "ssap://audio/getStatus".
+func service(uri string) string {
+	s := strings.TrimPrefix(uri, "ssap://")
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// Supports reports whether the TV supports the endpoint uri. The
+// com.webos.* services are not listed by the TV, so they are assumed to
+// be supported unless they need a missing feature. Everything is assumed
+// to be supported if c is nil.
+func (c *Capabilities) Supports(uri string) bool {
+	if c == nil {
+		return true
+	}
+	if f, ok := requiredFeatures[uri]; ok && c.Features != nil && !c.Features[f] {
+		return false
+	}
+	s := service(uri)
+	if c.Services == nil || strings.HasPrefix(s, "com.webos.") {
+		return true
+	}
+	_, ok := c.Services[s]
+	return ok
+}
+
+// DetectCapabilities queries the services and the features of the TV and
+// stores them for Capabilities and Supports. Missing information is
+// treated as unknown, so that everything is assumed to be supported.
+func (tv *Tv) DetectCapabilities(ctx context.Context) (c *Capabilities, err error) {
+	c = new(Capabilities)
+	services, err := tv.ApiGetServiceListContext(ctx)
+	if err != nil && !IsNotFound(err) {
+		return nil, errors.Wrap(err, "service list")
+	}
+	if err == nil {
+		c.Services = make(map[string]int)
+		for _, s := range services {
+			c.Services[s.Name] = s.Version
+		}
+	}
+	info, err := tv.SystemGetSystemInfoContext(ctx)
+	if err != nil && !IsNotFound(err) {
+		return nil, errors.Wrap(err, "system info")
+	}
+	if err == nil {
+		c.Features = info.Features
+		c.ReceiverType = info.ReceiverType
+	}
+
+	tv.wsMutex.Lock()
+	tv.caps = c
+	tv.wsMutex.Unlock()
+	return c, nil
+}
+
+// Capabilities returns the Capabilities found by DetectCapabilities, or
+// nil if they have not been detected.
+func (tv *Tv) Capabilities() *Capabilities {
+	tv.wsMutex.Lock()
+	defer tv.wsMutex.Unlock()
+	return tv.caps
+}
+
+// Supports reports whether the TV supports the endpoint uri according to
+// the detected Capabilities.
+func (tv *Tv) Supports(uri string) bool {
+	return tv.Capabilities().Supports(uri)
+}
+
+// checkSupported returns an error caused by ErrUnsupported if the TV does
+// not support uri.
+func (tv *Tv) checkSupported(uri string) error {
+	if !tv.Supports(uri) {
+		return errors.Wrap(ErrUnsupported, uri)
+	}
+	return nil
+}
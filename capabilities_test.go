@@ -0,0 +1,68 @@
+package webostv_test
+
+import (
+	"context"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse(webostv.UriApiGetServiceList, webostv.Payload{
+		"services": []webostv.Payload{
+			{"name": "api", "version": 1},
+			{"name": "system", "version": 1},
+			{"name": "tv", "version": 1},
+		},
+	})
+	s.HandleResponse(webostv.UriSystemGetSystemInfo, webostv.Payload{
+		"features":     webostv.Payload{"3d": false, "dvr": true},
+		"receiverType": "atsc",
+	})
+	s.HandleResponse(webostv.UriTvChannelUp, nil)
+
+	tv := dialTestTv(t, s)
+	defer tv.Close()
+	if !tv.Supports(webostv.UriAudioSetVolume) {
+		t.Error("endpoint unsupported before capabilities are detected")
+	}
+	_, err := tv.RegisterWithOptions(context.Background(), webostv.RegisterOptions{DetectCapabilities: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := tv.Capabilities()
+	if c == nil || c.ReceiverType != "atsc" || !c.Features["dvr"] || c.Services["tv"] != 1 {
+		t.Fatalf("unexpected capabilities %+v", c)
+	}
+
+	err = tv.AudioSetVolume(1)
+	if !webostv.IsUnsupported(err) {
+		t.Errorf("expected ErrUnsupported from unlisted service, got %v", err)
+	}
+	err = tv.AudioMonitorStatus(func(webostv.AudioStatus) error { return nil }, nil)
+	if !webostv.IsUnsupported(err) {
+		t.Errorf("expected ErrUnsupported from monitor of unlisted service, got %v", err)
+	}
+	err = tv.Set3DOn()
+	if !webostv.IsUnsupported(err) {
+		t.Errorf("expected ErrUnsupported without 3d feature, got %v", err)
+	}
+	_, _, err = tv.Get3DStatus()
+	if !webostv.IsUnsupported(err) {
+		t.Errorf("expected ErrUnsupported from custom wrapper, got %v", err)
+	}
+	err = tv.TvChannelUp()
+	if err != nil {
+		t.Errorf("listed service failed: %v", err)
+	}
+	err = tv.ImeSendEnterKey()
+	if !webostv.IsNotFound(err) {
+		t.Errorf("expected request to unlisted com.webos service to be sent, got %v", err)
+	}
+	_, err = tv.Request(webostv.UriAudioSetVolume, nil)
+	if !webostv.IsNotFound(err) {
+		t.Errorf("expected raw request to be sent, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package webostv
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// CertificateMismatchError is returned when the TLS certificate presented
+// by the TV does not match the pinned fingerprint. It means that either
+// the certificate of the TV has changed or that someone is intercepting
+// the connection.
+type CertificateMismatchError struct {
+	Address  string
+	Expected string // pinned fingerprint
+	Actual   string // fingerprint of the presented certificate
+}
+
+func (e *CertificateMismatchError) Error() string {
+	return "TLS certificate of " + e.Address + " does not match the pinned fingerprint " +
+		e.Expected + " (got " + e.Actual + ")"
+}
+
+// IsCertificateMismatch reports whether err is a CertificateMismatchError.
+func IsCertificateMismatch(err error) bool {
+	_, ok := errors.Cause(err).(*CertificateMismatchError)
+	return ok
+}
+
+// CertificateFingerprint returns the hex encoded SHA-256 hash of the DER
+// encoding of cert.
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeFingerprint makes fingerprints in the common "AB:CD:..." form
+// comparable with the ones returned by CertificateFingerprint.
+func normalizeFingerprint(fp string) string {
+	return strings.ToLower(strings.Replace(fp, ":", "", -1))
+}
+
+// connFingerprint returns the fingerprint of the certificate of the peer
+// of ws or an empty string if ws does not use TLS.
+func connFingerprint(ws *websocket.Conn) string {
+	tlsConn, ok := ws.UnderlyingConn().(*tls.Conn)
+	if !ok {
+		return ""
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return ""
+	}
+	return CertificateFingerprint(certs[0])
+}
+
+// verifyFingerprint checks the certificate of ws against the expected
+// fingerprint. Connections without TLS and an empty expected fingerprint
+// are accepted. The fingerprint of the connection is returned.
+func verifyFingerprint(ws *websocket.Conn, address, expected string) (fingerprint string, err error) {
+	fingerprint = connFingerprint(ws)
+	return fingerprint, checkFingerprint(address, expected, fingerprint)
+}
+
+func checkFingerprint(address, expected, actual string) error {
+	if actual == "" || expected == "" || normalizeFingerprint(expected) == actual {
+		return nil
+	}
+	return &CertificateMismatchError{
+		Address:  address,
+		Expected: expected,
+		Actual:   actual,
+	}
+}
+
+// pinnedFingerprint returns the fingerprint pinned for address, if any.
+func (dialer *Dialer) pinnedFingerprint(address string) string {
+	if dialer.PinnedFingerprint == nil {
+		return ""
+	}
+	return dialer.PinnedFingerprint(address)
+}
+
+// Fingerprint returns the fingerprint of the TLS certificate of the TV,
+// see CertificateFingerprint. It is empty if TLS is not used. Store it
+// after the first successful registration and return it from
+// Dialer.PinnedFingerprint to detect if the certificate changes later.
+func (tv *Tv) Fingerprint() string {
+	tv.wsMutex.Lock()
+	defer tv.wsMutex.Unlock()
+	return tv.fingerprint
+}
@@ -0,0 +1,58 @@
+package webostv_test
+
+import (
+	"context"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"strings"
+	"testing"
+)
+
+func TestCertificatePinning(t *testing.T) {
+	s := webostvtest.NewTLSServer()
+	defer s.Close()
+
+	dialer := s.Dialer()
+	tv, err := dialer.Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go tv.MessageHandler()
+	result, err := tv.RegisterWithOptions(context.Background(), webostv.RegisterOptions{})
+	tv.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp := webostv.CertificateFingerprint(s.Certificate()); result.Fingerprint != fp {
+		t.Fatalf("got fingerprint %q, expected %q", result.Fingerprint, fp)
+	}
+
+	pinned := result.Fingerprint
+	dialer.PinnedFingerprint = func(address string) string {
+		return pinned
+	}
+	tv, err = dialer.Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go tv.MessageHandler()
+	_, err = tv.Register(result.ClientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tv.Pointer().Press(webostv.ButtonHome)
+	tv.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinned = strings.Repeat("00", 32)
+	_, err = dialer.Dial(s.Address)
+	if !webostv.IsCertificateMismatch(err) {
+		t.Fatalf("expected CertificateMismatchError, got %v", err)
+	}
+	_, err = dialer.DialPointerSocket("wss://" + s.Address + "/pointer")
+	if !webostv.IsCertificateMismatch(err) {
+		t.Fatalf("expected CertificateMismatchError, got %v", err)
+	}
+}
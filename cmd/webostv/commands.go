@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type command struct {
+	name    string // one or two words, e.g. "volume set"
+	args    string // arguments for the usage message
+	nargs   int    // number of arguments, -1 for one or more
+	help    string
+	offline bool // the command does not connect to the TV
+	run     func(ctx context.Context, s *session, args []string) (result interface{}, err error)
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "volume get", help: "print the volume", run: volumeGet},
+		{name: "volume set", args: "VOLUME", nargs: 1, help: "set the volume", run: volumeSet},
+		{name: "volume up", help: "increase the volume", run: action((*webostv.Tv).AudioVolumeUpContext)},
+		{name: "volume down", help: "decrease the volume", run: action((*webostv.Tv).AudioVolumeDownContext)},
+		{name: "volume mute", help: "mute the sound", run: mute(true)},
+		{name: "volume unmute", help: "unmute the sound", run: mute(false)},
+		{name: "channel get", help: "print the current channel", run: channelGet},
+		{name: "channel open", args: "NUMBER", nargs: 1, help: "switch to the channel", run: channelOpen},
+		{name: "channel up", help: "switch to the next channel", run: action((*webostv.Tv).TvChannelUpContext)},
+		{name: "channel down", help: "switch to the previous channel", run: action((*webostv.Tv).TvChannelDownContext)},
+		{name: "channel list", help: "list the channels", run: channelList},
+		{name: "app current", help: "print the app in the foreground", run: appCurrent},
+		{name: "app list", help: "list the apps", run: appList},
+		{name: "app launch", args: "APP", nargs: 1, help: "launch the app with the given id or title", run: appLaunch},
+		{name: "input list", help: "list the external inputs", run: inputList},
+		{name: "input switch", args: "INPUT", nargs: 1, help: "switch to the input with the given id or label", run: inputSwitch},
+		{name: "media play", help: "play", run: action((*webostv.Tv).MediaControlsPlayContext)},
+		{name: "media pause", help: "pause", run: action((*webostv.Tv).MediaControlsPauseContext)},
+		{name: "media stop", help: "stop", run: action((*webostv.Tv).MediaControlsStopContext)},
+		{name: "media rewind", help: "rewind", run: action((*webostv.Tv).MediaControlsRewindContext)},
+		{name: "media fastforward", help: "fast forward", run: action((*webostv.Tv).MediaControlsFastForwardContext)},
+		{name: "button", args: "BUTTON...", nargs: -1, help: "press remote control buttons", run: button},
+		{name: "toast", args: "MESSAGE", nargs: 1, help: "show a notification on the screen", run: toast},
+		{name: "info", help: "print information about the TV", run: info},
+		{name: "off", help: "turn off the TV", run: action((*webostv.Tv).SystemTurnOffContext)},
+		{name: "on", help: "turn on the TV with Wake-on-LAN (--mac unless connected before)", offline: true, run: on},
+	}
+}
+
+// findCommand finds the command named by the first one or two words of
+// args and checks the number of its arguments.
+func findCommand(args []string) (cmd *command, cmdArgs []string, err error) {
+	if len(args) == 0 {
+		return nil, nil, errors.New("command is missing")
+	}
+	for i := range commands {
+		words := strings.Fields(commands[i].name)
+		if len(args) < len(words) || strings.Join(args[:len(words)], " ") != commands[i].name {
+			continue
+		}
+		cmd = &commands[i]
+		cmdArgs = args[len(words):]
+		switch {
+		case cmd.nargs < 0 && len(cmdArgs) == 0:
+			return nil, nil, errors.New(cmd.name + ": argument is missing")
+		case cmd.nargs >= 0 && len(cmdArgs) != cmd.nargs:
+			return nil, nil, errors.New(cmd.name + ": expected " + strconv.Itoa(cmd.nargs) + " argument(s)")
+		}
+		return cmd, cmdArgs, nil
+	}
+	return nil, nil, errors.New("unknown command: " + strings.Join(args, " "))
+}
+
+// action returns a command which calls f without arguments.
+func action(f func(tv *webostv.Tv, ctx context.Context) error) func(context.Context, *session, []string) (interface{}, error) {
+	return func(ctx context.Context, s *session, args []string) (interface{}, error) {
+		return nil, f(s.tv, ctx)
+	}
+}
+
+func mute(mute bool) func(context.Context, *session, []string) (interface{}, error) {
+	return func(ctx context.Context, s *session, args []string) (interface{}, error) {
+		return nil, s.tv.AudioSetMuteContext(ctx, mute)
+	}
+}
+
+type volumeResult struct {
+	Volume int  `json:"volume"`
+	Mute   bool `json:"mute"`
+}
+
+func (r volumeResult) String() string {
+	if r.Mute {
+		return strconv.Itoa(r.Volume) + " (muted)"
+	}
+	return strconv.Itoa(r.Volume)
+}
+
+func volumeGet(ctx context.Context, s *session, args []string) (interface{}, error) {
+	as, err := s.tv.AudioGetStatusContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return volumeResult{Volume: as.Volume, Mute: as.Mute}, nil
+}
+
+func volumeSet(ctx context.Context, s *session, args []string) (interface{}, error) {
+	volume, err := strconv.Atoi(args[0])
+	if err != nil || volume < 0 || volume > 100 {
+		return nil, withStatus(exitUsage, errors.New("invalid volume: "+args[0]))
+	}
+	return nil, s.tv.AudioSetVolumeContext(ctx, volume)
+}
+
+type channelResult struct {
+	Id     string `json:"id"`
+	Number string `json:"number"`
+	Name   string `json:"name"`
+}
+
+func (r channelResult) String() string {
+	return r.Number + "\t" + r.Name
+}
+
+type channelListResult []channelResult
+
+func (r channelListResult) String() string {
+	lines := make([]string, len(r))
+	for i, ch := range r {
+		lines[i] = ch.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func channelGet(ctx context.Context, s *session, args []string) (interface{}, error) {
+	cur, err := s.tv.TvGetCurrentChannelContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return channelResult{Id: cur.ChannelId, Number: cur.ChannelNumber, Name: cur.ChannelName}, nil
+}
+
+func channelOpen(ctx context.Context, s *session, args []string) (interface{}, error) {
+	return nil, s.tv.TvOpenChannelNumberContext(ctx, args[0])
+}
+
+func channelList(ctx context.Context, s *session, args []string) (interface{}, error) {
+	list, err := s.tv.TvGetChannelListContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(channelListResult, len(list))
+	for i, ch := range list {
+		result[i] = channelResult{Id: ch.ChannelId, Number: ch.ChannelNumber, Name: ch.ChannelName}
+	}
+	return result, nil
+}
+
+// item is an app or an input. Its text form is "id<tab>name".
+type item struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (it item) String() string {
+	return it.Id + "\t" + it.Name
+}
+
+type itemList []item
+
+func (l itemList) String() string {
+	lines := make([]string, len(l))
+	for i, it := range l {
+		lines[i] = it.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// find returns the item whose id matches name exactly or whose name
+// matches it ignoring case. A unique partial match of the name is
+// accepted too.
+func (l itemList) find(kind, name string) (it item, err error) {
+	var partial []item
+	for _, it := range l {
+		if it.Id == name || strings.EqualFold(it.Name, name) {
+			return it, nil
+		}
+		if strings.Contains(strings.ToLower(it.Name), strings.ToLower(name)) {
+			partial = append(partial, it)
+		}
+	}
+	switch len(partial) {
+	case 0:
+		return it, withStatus(exitNotFound, errors.New(kind+" not found: "+name))
+	case 1:
+		return partial[0], nil
+	}
+	return it, withStatus(exitNotFound, errors.New(kind+" is ambiguous: "+name))
+}
+
+func listApps(ctx context.Context, tv *webostv.Tv) (l itemList, err error) {
+	lps, _, err := tv.ApplicationManagerListLaunchPointsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, lp := range lps {
+		l = append(l, item{Id: lp.Id, Name: lp.Title})
+	}
+	return l, nil
+}
+
+func appCurrent(ctx context.Context, s *session, args []string) (interface{}, error) {
+	info, err := s.tv.ApplicationManagerGetForegroundAppInfoContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return item{Id: info.AppId}, nil
+}
+
+func appList(ctx context.Context, s *session, args []string) (interface{}, error) {
+	return listApps(ctx, s.tv)
+}
+
+func appLaunch(ctx context.Context, s *session, args []string) (interface{}, error) {
+	apps, err := listApps(ctx, s.tv)
+	if err != nil {
+		return nil, err
+	}
+	app, err := apps.find("app", args[0])
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.tv.ApplicationManagerLaunchContext(ctx, app.Id, nil)
+	return app, err
+}
+
+func listInputs(ctx context.Context, tv *webostv.Tv) (l itemList, err error) {
+	inputs, err := tv.TvGetExternalInputListContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, in := range inputs {
+		l = append(l, item{Id: in.Id, Name: in.Label})
+	}
+	return l, nil
+}
+
+func inputList(ctx context.Context, s *session, args []string) (interface{}, error) {
+	return listInputs(ctx, s.tv)
+}
+
+func inputSwitch(ctx context.Context, s *session, args []string) (interface{}, error) {
+	inputs, err := listInputs(ctx, s.tv)
+	if err != nil {
+		return nil, err
+	}
+	in, err := inputs.find("input", args[0])
+	if err != nil {
+		return nil, err
+	}
+	return in, s.tv.TvSwitchInputContext(ctx, in.Id)
+}
+
+func button(ctx context.Context, s *session, args []string) (interface{}, error) {
+	var buttons []webostv.Button
+	for _, arg := range args {
+		b, err := webostv.ParseButton(arg)
+		if err != nil {
+			return nil, withStatus(exitNotFound, err)
+		}
+		buttons = append(buttons, b)
+	}
+	ps := s.tv.Pointer()
+	for i, b := range buttons {
+		if i > 0 {
+			// give the TV time to react before the next press
+			select {
+			case <-time.After(webostv.KeyRepeatInterval):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		err := ps.PressContext(ctx, b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func toast(ctx context.Context, s *session, args []string) (interface{}, error) {
+	_, err := s.tv.SystemNotificationsCreateToastContext(ctx, args[0])
+	return nil, err
+}
+
+type infoResult struct {
+	ModelName       string `json:"modelName"`
+	ProductName     string `json:"productName"`
+	FirmwareVersion string `json:"firmwareVersion"`
+	Country         string `json:"country"`
+	MAC             string `json:"mac"`
+}
+
+func (r infoResult) String() string {
+	return fmt.Sprintf("model: %s\nproduct: %s\nfirmware: %s\ncountry: %s\nmac: %s",
+		r.ModelName, r.ProductName, r.FirmwareVersion, r.Country, r.MAC)
+}
+
+func info(ctx context.Context, s *session, args []string) (interface{}, error) {
+	sw, err := s.tv.GetCurrentSWInformationContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sys, err := s.tv.SystemGetSystemInfoContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return infoResult{
+		ModelName:       sys.ModelName,
+		ProductName:     sw.ProductName,
+		FirmwareVersion: sw.MajorVer + "." + sw.MinorVer,
+		Country:         sw.Country,
+		MAC:             sw.DeviceId,
+	}, nil
+}
+
+func on(ctx context.Context, s *session, args []string) (interface{}, error) {
+	err := webostv.DefaultDialer.PowerOn(ctx, s.address, s.mac, "", "")
+	if errors.Cause(err) == webostv.ErrUnknownMAC {
+		return nil, withStatus(exitUsage, errors.New("MAC address of the TV is not known, use --mac"))
+	}
+	if err != nil && !webostv.IsCertificateMismatch(err) {
+		err = withStatus(exitConnect, err)
+	}
+	return nil, err
+}
@@ -0,0 +1,226 @@
+// Command webostv controls an LG WebOS TV from the command line. It is
+// meant for shell scripts and cron jobs: each invocation runs a single
+// command, such as "webostv volume set 12", and exits with a status which
+// tells what went wrong, if anything.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/ogier/pflag"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+const DefaultAddress = "LGsmartTV.lan"
+
+// exit statuses
+const (
+	exitOK       = 0
+	exitFailure  = 1 // the TV returned an error
+	exitUsage    = 2 // invalid command line
+	exitConnect  = 3 // the TV could not be reached
+	exitAuth     = 4 // pairing or permission denied, or certificate changed
+	exitNotFound = 5 // unknown app, input or button, or unsupported by the TV
+)
+
+// exitError is an error with the exit status it causes.
+type exitError struct {
+	status int
+	err    error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func withStatus(status int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{status, err}
+}
+
+// exitStatus returns the exit status for err.
+func exitStatus(err error) int {
+	if e, ok := err.(*exitError); ok {
+		return e.status
+	}
+	switch {
+	case err == nil:
+		return exitOK
+	case webostv.IsNotFound(err):
+		return exitNotFound
+	case webostv.IsPermissionDenied(err), webostv.IsPairingRejected(err),
+		webostv.IsCertificateMismatch(err), errors.Cause(err) == webostv.ErrRegistrationFailed:
+		return exitAuth
+	case isConnectError(err):
+		return exitConnect
+	}
+	return exitFailure
+}
+
+// isConnectError reports whether err tells that the TV did not respond or
+// the connection to it failed.
+func isConnectError(err error) bool {
+	switch cause := errors.Cause(err); cause {
+	case webostv.ErrTimeout, webostv.ErrNoResponse, context.DeadlineExceeded, io.EOF, io.ErrUnexpectedEOF:
+		return true
+	}
+	switch errors.Cause(err).(type) {
+	case net.Error, *websocket.CloseError:
+		return true
+	}
+	return false
+}
+
+// session holds the command line options and the connection to the TV.
+type session struct {
+	address string
+	mac     string
+	record  string // capture file, see webostv.Recorder
+	tv      *webostv.Tv
+	capture *os.File
+}
+
+func (s *session) connect(ctx context.Context) (err error) {
+	s.tv, err = webostv.DefaultDialer.DialContext(ctx, s.address)
+	if err != nil {
+		return withStatus(exitConnect, err)
+	}
+	if s.record != "" {
+		s.capture, err = os.OpenFile(s.record, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			s.tv.Close()
+			return err
+		}
+		s.tv.SetRecorder(webostv.NewRecorder(s.capture))
+	}
+	go s.tv.MessageHandler()
+
+	_, err = s.tv.RegisterWithOptions(ctx, webostv.RegisterOptions{})
+	if err != nil {
+		s.close()
+		return err
+	}
+	return nil
+}
+
+func (s *session) close() {
+	s.tv.Close()
+	if s.capture != nil {
+		s.capture.Close()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:", os.Args[0], "[OPTION]... COMMAND [ARGUMENT]...")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "The following COMMANDS are available:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-30s %s\n", cmd.name+" "+cmd.args, cmd.help)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "The following OPTIONS are available:")
+	pflag.PrintDefaults()
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Exit status is 0 on success, 1 if the TV returned an error, 2 for invalid")
+	fmt.Fprintln(os.Stderr, "usage, 3 if the TV could not be reached, 4 if pairing or permission was")
+	fmt.Fprintln(os.Stderr, "denied and 5 if the app, input or button was not found or the TV does not")
+	fmt.Fprintln(os.Stderr, "support the command.")
+}
+
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func main() {
+	pflag.Usage = usage
+	address := pflag.StringP("address", "a", getenv("WEBOSTV_ADDRESS", DefaultAddress),
+		"name or IP address of the TV (env WEBOSTV_ADDRESS)")
+	mac := pflag.String("mac", os.Getenv("WEBOSTV_MAC"),
+		"MAC address of the TV for the \"on\" command if it is not remembered (env WEBOSTV_MAC)")
+	jsonOutput := pflag.Bool("json", false, "print the result as JSON")
+	timeout := pflag.Duration("timeout", 30*time.Second, "maximum time to run the command")
+	record := pflag.String("record", "", "append the messages exchanged with the TV to a capture file")
+	pflag.Parse()
+
+	cmd, args, err := findCommand(pflag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		fmt.Fprintln(os.Stderr, "Try", os.Args[0], "--help")
+		os.Exit(exitUsage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	s := &session{
+		address: *address,
+		mac:     *mac,
+		record:  *record,
+	}
+	result, err := run(ctx, s, cmd, args)
+	cancel()
+
+	status := exitStatus(err)
+	if *jsonOutput {
+		printJSON(result, err, status)
+	} else {
+		printText(result, err)
+	}
+	os.Exit(status)
+}
+
+func run(ctx context.Context, s *session, cmd *command, args []string) (result interface{}, err error) {
+	ks, err := webostv.NewFileKeyStore("")
+	if err != nil {
+		return nil, err
+	}
+	webostv.DefaultDialer.KeyStore = ks
+
+	if !cmd.offline {
+		err = s.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer s.close()
+	}
+	return cmd.run(ctx, s, args)
+}
+
+func printJSON(result interface{}, err error, status int) {
+	out := struct {
+		Ok     bool        `json:"ok"`
+		Result interface{} `json:"result,omitempty"`
+		Error  string      `json:"error,omitempty"`
+		Status int         `json:"status"`
+	}{
+		Ok:     err == nil,
+		Result: result,
+		Status: status,
+	}
+	if err != nil {
+		out.Error = err.Error()
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(out)
+}
+
+func printText(result interface{}, err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return
+	}
+	if result != nil {
+		fmt.Println(result)
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// handlerFunc handles an API request. The result is sent to the client as
+// JSON. A nil result is sent as {"ok": true}.
+type handlerFunc func(ctx context.Context, r *http.Request) (result interface{}, err error)
+
+// route maps the HTTP methods of a path to their handlers.
+type route map[string]handlerFunc
+
+type api struct {
+	tv     *webostv.Tv   // set by setTv
+	ready  chan struct{} // closed by setTv
+	hub    *hub
+	routes map[string]route
+	mux    *http.ServeMux
+}
+
+// badRequestError is returned for invalid requests from the client.
+type badRequestError struct {
+	msg string
+}
+
+func (e *badRequestError) Error() string {
+	return e.msg
+}
+
+func newAPI(hub *hub) *api {
+	a := &api{
+		ready: make(chan struct{}),
+		hub:   hub,
+		mux:   http.NewServeMux(),
+	}
+	a.routes = map[string]route{
+		"/api/audio":             {"GET": a.getAudio},
+		"/api/audio/volume":      {"PUT": a.setVolume},
+		"/api/audio/volume/up":   {"POST": a.volumeUp},
+		"/api/audio/volume/down": {"POST": a.volumeDown},
+		"/api/audio/mute":        {"PUT": a.setMute},
+		"/api/channels":          {"GET": a.getChannels},
+		"/api/channels/current":  {"GET": a.getCurrentChannel, "PUT": a.openChannel},
+		"/api/channels/up":       {"POST": a.channelUp},
+		"/api/channels/down":     {"POST": a.channelDown},
+		"/api/inputs":            {"GET": a.getInputs},
+		"/api/inputs/current":    {"PUT": a.switchInput},
+		"/api/apps":              {"GET": a.getApps},
+		"/api/apps/foreground":   {"GET": a.getForegroundApp},
+		"/api/apps/launch":       {"POST": a.launchApp},
+		"/api/launcher/open":     {"POST": a.launcherOpen},
+		"/api/launcher/close":    {"POST": a.launcherClose},
+		"/api/toast":             {"POST": a.toast},
+		"/api/power/off":         {"POST": a.powerOff},
+	}
+	for path, rt := range a.routes {
+		a.mux.Handle(path, a.handle(rt))
+	}
+	a.mux.HandleFunc("/api/events", a.serveEvents)
+	return a
+}
+
+// setTv makes the API serve requests with tv. Until it is called the
+// requests which need the TV are answered with 503 Service Unavailable.
+func (a *api) setTv(tv *webostv.Tv) {
+	a.tv = tv
+	close(a.ready)
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mux.ServeHTTP(w, r)
+}
+
+func (a *api) handle(rt route) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := rt[r.Method]
+		if !ok {
+			methods := make([]string, 0, len(rt))
+			for m := range rt {
+				methods = append(methods, m)
+			}
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			writeJSON(w, http.StatusMethodNotAllowed, errorBody(errors.New("method not allowed")))
+			return
+		}
+		select {
+		case <-a.ready:
+		default:
+			writeJSON(w, http.StatusServiceUnavailable, errorBody(errors.New("TV is not connected")))
+			return
+		}
+		result, err := f(r.Context(), r)
+		if err != nil {
+			writeJSON(w, errorStatus(err), errorBody(err))
+			return
+		}
+		if result == nil {
+			result = map[string]bool{"ok": true}
+		}
+		writeJSON(w, http.StatusOK, result)
+	})
+}
+
+// errorStatus returns the HTTP status code for err.
+func errorStatus(err error) int {
+	switch {
+	case webostv.IsNotFound(err):
+		return http.StatusNotFound
+	case webostv.IsPermissionDenied(err):
+		return http.StatusForbidden
+	case webostv.IsUnsupported(err):
+		return http.StatusNotImplemented
+	case errors.Cause(err) == webostv.ErrTimeout:
+		return http.StatusGatewayTimeout
+	}
+	if _, ok := err.(*badRequestError); ok {
+		return http.StatusBadRequest
+	}
+	return http.StatusBadGateway
+}
+
+func errorBody(err error) interface{} {
+	return map[string]string{"error": err.Error()}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Print("error writing response: ", err)
+	}
+}
+
+// decodeBody decodes the JSON request body to v.
+func decodeBody(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		return &badRequestError{"invalid request body: " + err.Error()}
+	}
+	return nil
+}
+
+func (a *api) getAudio(ctx context.Context, r *http.Request) (interface{}, error) {
+	return a.tv.AudioGetStatusContext(ctx)
+}
+
+func (a *api) setVolume(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req struct {
+		Volume *int `json:"volume"`
+	}
+	err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Volume == nil || *req.Volume < 0 || *req.Volume > 100 {
+		return nil, &badRequestError{"volume must be between 0 and 100"}
+	}
+	return nil, a.tv.AudioSetVolumeContext(ctx, *req.Volume)
+}
+
+func (a *api) volumeUp(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, a.tv.AudioVolumeUpContext(ctx)
+}
+
+func (a *api) volumeDown(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, a.tv.AudioVolumeDownContext(ctx)
+}
+
+func (a *api) setMute(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req struct {
+		Mute bool `json:"mute"`
+	}
+	err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	return nil, a.tv.AudioSetMuteContext(ctx, req.Mute)
+}
+
+func (a *api) getChannels(ctx context.Context, r *http.Request) (interface{}, error) {
+	return a.tv.TvGetChannelListContext(ctx)
+}
+
+func (a *api) getCurrentChannel(ctx context.Context, r *http.Request) (interface{}, error) {
+	return a.tv.TvGetCurrentChannelContext(ctx)
+}
+
+func (a *api) openChannel(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req struct {
+		ChannelId     string `json:"channelId"`
+		ChannelNumber string `json:"channelNumber"`
+	}
+	err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case req.ChannelId != "":
+		return nil, a.tv.TvOpenChannelIdContext(ctx, req.ChannelId)
+	case req.ChannelNumber != "":
+		return nil, a.tv.TvOpenChannelNumberContext(ctx, req.ChannelNumber)
+	}
+	return nil, &badRequestError{"channelId or channelNumber is required"}
+}
+
+func (a *api) channelUp(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, a.tv.TvChannelUpContext(ctx)
+}
+
+func (a *api) channelDown(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, a.tv.TvChannelDownContext(ctx)
+}
+
+func (a *api) getInputs(ctx context.Context, r *http.Request) (interface{}, error) {
+	return a.tv.TvGetExternalInputListContext(ctx)
+}
+
+func (a *api) switchInput(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req struct {
+		InputId string `json:"inputId"`
+	}
+	err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.InputId == "" {
+		return nil, &badRequestError{"inputId is required"}
+	}
+	return nil, a.tv.TvSwitchInputContext(ctx, req.InputId)
+}
+
+func (a *api) getApps(ctx context.Context, r *http.Request) (interface{}, error) {
+	list, _, err := a.tv.ApplicationManagerListLaunchPointsContext(ctx)
+	return list, err
+}
+
+func (a *api) getForegroundApp(ctx context.Context, r *http.Request) (interface{}, error) {
+	return a.tv.ApplicationManagerGetForegroundAppInfoContext(ctx)
+}
+
+func (a *api) launchApp(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req struct {
+		Id     string          `json:"id"`
+		Params webostv.Payload `json:"params"`
+	}
+	err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Id == "" {
+		return nil, &badRequestError{"id is required"}
+	}
+	sessionId, err := a.tv.SystemLauncherLaunchContext(ctx, req.Id, req.Params)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"sessionId": sessionId}, nil
+}
+
+func (a *api) launcherOpen(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req struct {
+		Url string `json:"url"`
+	}
+	err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Url == "" {
+		return nil, &badRequestError{"url is required"}
+	}
+	appId, sessionId, err := a.tv.SystemLauncherOpenContext(ctx, req.Url)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"appId": appId, "sessionId": sessionId}, nil
+}
+
+func (a *api) launcherClose(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req struct {
+		SessionId string `json:"sessionId"`
+	}
+	err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.SessionId == "" {
+		return nil, &badRequestError{"sessionId is required"}
+	}
+	return nil, a.tv.SystemLauncherCloseContext(ctx, req.SessionId)
+}
+
+func (a *api) toast(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req struct {
+		Message string `json:"message"`
+	}
+	err := decodeBody(r, &req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Message == "" {
+		return nil, &badRequestError{"message is required"}
+	}
+	toastId, err := a.tv.SystemNotificationsCreateToastContext(ctx, req.Message)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"toastId": toastId}, nil
+}
+
+func (a *api) powerOff(ctx context.Context, r *http.Request) (interface{}, error) {
+	return nil, a.tv.SystemTurnOffContext(ctx)
+}
+
+// serveEvents streams the status changes of the TV as Server-Sent Events.
+// The event names are "audio", "channel" and "app".
+func (a *api) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, errorBody(errors.New("method not allowed")))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, errorBody(errors.New("streaming is not supported")))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := a.hub.subscribe()
+	defer a.hub.unsubscribe(ch)
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// clientQueueSize is the number of events buffered for each event stream
+// client. Events are dropped for clients which do not keep up.
+const clientQueueSize = 16
+
+type event struct {
+	name string
+	data []byte // JSON
+}
+
+// hub distributes events to the connected event stream clients. The
+// latest event of each name is kept and sent to new clients first, so
+// that they see the current state immediately.
+type hub struct {
+	sync.Mutex
+	clients map[chan event]bool
+	latest  map[string]event
+	order   []string // names of the latest events in arrival order
+	closed  bool
+}
+
+func newHub() *hub {
+	return &hub{
+		clients: make(map[chan event]bool),
+		latest:  make(map[string]event),
+	}
+}
+
+func (h *hub) publish(name string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Print("JSON marshal error: ", err)
+		return
+	}
+	ev := event{name: name, data: data}
+
+	h.Lock()
+	defer h.Unlock()
+	if _, ok := h.latest[name]; !ok {
+		h.order = append(h.order, name)
+	}
+	h.latest[name] = ev
+	for ch := range h.clients {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// subscribe returns a channel which receives the events. The channel is
+// closed by unsubscribe or when the hub is closed.
+func (h *hub) subscribe() chan event {
+	h.Lock()
+	defer h.Unlock()
+	ch := make(chan event, clientQueueSize+len(h.order))
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	for _, name := range h.order {
+		select {
+		case ch <- h.latest[name]:
+		default:
+		}
+	}
+	h.clients[ch] = true
+	return ch
+}
+
+func (h *hub) unsubscribe(ch chan event) {
+	h.Lock()
+	defer h.Unlock()
+	if h.clients[ch] {
+		delete(h.clients, ch)
+		close(ch)
+	}
+}
+
+func (h *hub) close() {
+	h.Lock()
+	defer h.Unlock()
+	for ch := range h.clients {
+		close(ch)
+	}
+	h.clients = make(map[chan event]bool)
+	h.closed = true
+}
@@ -0,0 +1,219 @@
+// Command webostvd is a daemon which keeps a connection to an LG WebOS TV
+// and exposes it as a local HTTP/JSON API, so that programs which do not
+// speak the ssap websocket protocol can control the TV. Status changes are
+// streamed to clients as Server-Sent Events from /api/events. Metrics
+// about the TV and the connection are served in the Prometheus format
+// from /metrics.
+package main
+
+import (
+	"context"
+	"github.com/ogier/pflag"
+	"github.com/snabb/webostv"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const DefaultAddress = "LGsmartTV.lan"
+
+func main() {
+	address := pflag.StringP("address", "a", DefaultAddress, "name or IP address of the TV")
+	listen := pflag.StringP("listen", "l", "127.0.0.1:3080", "HTTP listen address")
+	debug := pflag.BoolP("debug", "d", false, "log the messages exchanged with the TV")
+	pflag.Parse()
+
+	ks, err := webostv.NewFileKeyStore("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	webostv.DefaultDialer.KeyStore = ks
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := newMetrics()
+	hub := newHub()
+	api := newAPI(hub)
+	reconnected := newNotifier()
+
+	// the TV is usually in standby when the daemon starts, so the API
+	// responds with 503 until the TV is connected
+	go func() {
+		hooks := m.hooks()
+		reconnect := hooks.Reconnect
+		hooks.Reconnect = func(err error) {
+			reconnect(err)
+			if err == nil {
+				reconnected.notify()
+			}
+		}
+		tv, err := connect(ctx, *address, *debug, hooks, cancel)
+		if err != nil {
+			return
+		}
+		m.setConnected(true)
+		api.setTv(tv)
+		startMonitors(ctx, tv, hub, m, reconnected)
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/", api)
+	mux.Handle("/metrics", m)
+	srv := &http.Server{
+		Addr:    *listen,
+		Handler: mux,
+	}
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		select {
+		case <-sigCh:
+		case <-ctx.Done():
+		}
+		hub.close()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	log.Print("listening on ", *listen)
+	err = srv.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+// connect dials and registers with the TV until it succeeds or ctx is
+// done, backing off like Tv.SupervisedMessageHandler. The connection is
+// supervised until ctx is done; stop is called if the supervision fails.
+func connect(ctx context.Context, address string, debug bool, hooks webostv.Hooks, stop func()) (tv *webostv.Tv, err error) {
+	delay := webostv.ReconnectMinDelay
+	for {
+		tv, err = dialAndRegister(ctx, address, debug, hooks, stop)
+		if err == nil {
+			log.Print("connected to the TV")
+			return tv, nil
+		}
+		log.Print("TV connection error: ", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		delay *= 2
+		if delay > webostv.ReconnectMaxDelay {
+			delay = webostv.ReconnectMaxDelay
+		}
+	}
+}
+
+func dialAndRegister(ctx context.Context, address string, debug bool, hooks webostv.Hooks, stop func()) (*webostv.Tv, error) {
+	tv, err := webostv.DefaultDialer.DialContext(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if debug {
+		tv.SetDebug(func(s string) { log.Print(s) })
+	}
+	tv.SetHooks(hooks)
+
+	registered := make(chan struct{})
+	go func() {
+		err := tv.SupervisedMessageHandler(ctx)
+		select {
+		case <-registered:
+		default:
+			// registration failed, connect tries again
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Print("TV message handler: ", err)
+		}
+		stop()
+	}()
+
+	_, err = tv.RegisterWithOptions(ctx, webostv.RegisterOptions{DetectCapabilities: true})
+	if err != nil {
+		tv.Close()
+		return nil, err
+	}
+	close(registered)
+	return tv, nil
+}
+
+// notifier wakes up the waiters each time notify is called.
+type notifier struct {
+	sync.Mutex
+	ch chan struct{}
+}
+
+func newNotifier() *notifier {
+	return &notifier{ch: make(chan struct{})}
+}
+
+// wait returns a channel which is closed by the next notify.
+func (n *notifier) wait() <-chan struct{} {
+	n.Lock()
+	defer n.Unlock()
+	return n.ch
+}
+
+func (n *notifier) notify() {
+	n.Lock()
+	close(n.ch)
+	n.ch = make(chan struct{})
+	n.Unlock()
+}
+
+// startMonitors feeds the status subscriptions of the TV to hub and m. A
+// monitor whose subscription ends is restarted when the TV is reconnected.
+func startMonitors(ctx context.Context, tv *webostv.Tv, hub *hub, m *metrics, reconnected *notifier) {
+	monitor := func(name string, f func() error) {
+		go func() {
+			for {
+				next := reconnected.wait()
+				err := f()
+				if ctx.Err() != nil {
+					return
+				}
+				log.Print(name, " monitor: ", err)
+				select {
+				case <-next:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	monitor("power", func() error {
+		return tv.PowerMonitorStateContext(ctx, func(ps webostv.PowerState) error {
+			m.setPower(ps)
+			return nil
+		})
+	})
+	monitor("audio", func() error {
+		return tv.AudioMonitorStatusContext(ctx, func(as webostv.AudioStatus) error {
+			hub.publish("audio", as)
+			m.setAudio(as)
+			return nil
+		})
+	})
+	monitor("channel", func() error {
+		return tv.TvMonitorCurrentChannelContext(ctx, func(cur webostv.TvCurrentChannel) error {
+			hub.publish("channel", cur)
+			m.setChannel(cur)
+			return nil
+		})
+	})
+	monitor("app", func() error {
+		return tv.ApplicationManagerMonitorForegroundAppInfoContext(ctx, func(info webostv.ForegroundAppInfo) error {
+			hub.publish("app", info)
+			m.setApp(info)
+			return nil
+		})
+	})
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// requestBuckets are the upper bounds of the request duration histogram
+// in seconds.
+var requestBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(requestBuckets))
+	}
+	for i, le := range requestBuckets {
+		if v <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// metrics collects the state of the TV and the health of the connection
+// and writes them in the Prometheus text exposition format. Hand written
+// to avoid pulling in the Prometheus client library.
+type metrics struct {
+	sync.Mutex
+
+	connected     bool
+	powerOn       float64
+	volume        float64
+	muted         bool
+	channelNumber float64
+	appId         string
+
+	requests      map[string]uint64     // by URI
+	errors        map[[2]string]uint64  // by URI and error code
+	durations     map[string]*histogram // by URI
+	subMessages   map[string]uint64     // by URI
+	subDropped    map[string]uint64     // by URI
+	disconnects   uint64
+	reconnects    uint64
+	resumeFailure uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		powerOn:       math.NaN(),
+		volume:        math.NaN(),
+		channelNumber: math.NaN(),
+		requests:      make(map[string]uint64),
+		errors:        make(map[[2]string]uint64),
+		durations:     make(map[string]*histogram),
+		subMessages:   make(map[string]uint64),
+		subDropped:    make(map[string]uint64),
+	}
+}
+
+// errorCode returns the value of the "code" label for err.
+func errorCode(err error) string {
+	switch cause := errors.Cause(err); cause {
+	case webostv.ErrTimeout:
+		return "timeout"
+	case webostv.ErrNoResponse:
+		return "no_response"
+	default:
+		if apiErr, ok := cause.(*webostv.APIError); ok {
+			if apiErr.ErrorCode != "" {
+				return apiErr.ErrorCode
+			}
+			if code := apiErr.StatusCode(); code != 0 {
+				return strconv.Itoa(code)
+			}
+			return "api"
+		}
+	}
+	return "other"
+}
+
+// hooks returns the Hooks which feed m.
+func (m *metrics) hooks() webostv.Hooks {
+	return webostv.Hooks{
+		Request: func(uri string, d time.Duration, err error) {
+			m.Lock()
+			defer m.Unlock()
+			m.requests[uri]++
+			if err != nil {
+				m.errors[[2]string{uri, errorCode(err)}]++
+			}
+			h := m.durations[uri]
+			if h == nil {
+				h = new(histogram)
+				m.durations[uri] = h
+			}
+			h.observe(d.Seconds())
+		},
+		SubscriptionMessage: func(uri string, dropped bool) {
+			m.Lock()
+			defer m.Unlock()
+			m.subMessages[uri]++
+			if dropped {
+				m.subDropped[uri]++
+			}
+		},
+		Disconnect: func(err error) {
+			m.Lock()
+			defer m.Unlock()
+			m.disconnects++
+			m.connected = false
+		},
+		Reconnect: func(err error) {
+			m.Lock()
+			defer m.Unlock()
+			m.reconnects++
+			if err != nil {
+				m.resumeFailure++
+			} else {
+				m.connected = true
+			}
+		},
+	}
+}
+
+func (m *metrics) setConnected(connected bool) {
+	m.Lock()
+	m.connected = connected
+	m.Unlock()
+}
+
+func (m *metrics) setPower(ps webostv.PowerState) {
+	m.Lock()
+	m.powerOn = boolValue(ps.IsOn())
+	m.Unlock()
+}
+
+func (m *metrics) setAudio(as webostv.AudioStatus) {
+	m.Lock()
+	m.volume = float64(as.Volume)
+	m.muted = as.Mute
+	m.Unlock()
+}
+
+func (m *metrics) setChannel(cur webostv.TvCurrentChannel) {
+	n, err := strconv.ParseFloat(cur.ChannelNumber, 64)
+	if err != nil {
+		n = math.NaN()
+	}
+	m.Lock()
+	m.channelNumber = n
+	m.Unlock()
+}
+
+func (m *metrics) setApp(info webostv.ForegroundAppInfo) {
+	m.Lock()
+	m.appId = info.AppId
+	m.Unlock()
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.writeTo(w)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labels(kv ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(kv); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(kv[i] + `="` + labelEscaper.Replace(kv[i+1]) + `"`)
+	}
+	return "{" + b.String() + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func header(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func sample(w io.Writer, name, labels string, v float64) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, formatValue(v))
+}
+
+func sortedKeys(m map[string]uint64) (keys []string) {
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *metrics) writeTo(w io.Writer) {
+	m.Lock()
+	defer m.Unlock()
+
+	header(w, "webostv_connected", "gauge", "Whether the connection to the TV is up.")
+	sample(w, "webostv_connected", "", boolValue(m.connected))
+	header(w, "webostv_power_on", "gauge", "Whether the TV reported that it is on, NaN if not known yet.")
+	sample(w, "webostv_power_on", "", m.powerOn)
+	header(w, "webostv_volume", "gauge", "Volume of the TV.")
+	sample(w, "webostv_volume", "", m.volume)
+	header(w, "webostv_muted", "gauge", "Whether the sound of the TV is muted.")
+	sample(w, "webostv_muted", "", boolValue(m.muted))
+	header(w, "webostv_channel_number", "gauge", "Number of the current channel.")
+	sample(w, "webostv_channel_number", "", m.channelNumber)
+	header(w, "webostv_foreground_app", "gauge", "App in the foreground, the value is always 1.")
+	if m.appId != "" {
+		sample(w, "webostv_foreground_app", labels("app_id", m.appId), 1)
+	}
+
+	header(w, "webostv_requests_total", "counter", "Number of ssap requests.")
+	for _, uri := range sortedKeys(m.requests) {
+		sample(w, "webostv_requests_total", labels("uri", uri), float64(m.requests[uri]))
+	}
+
+	header(w, "webostv_request_errors_total", "counter", "Number of failed ssap requests.")
+	errKeys := make([][2]string, 0, len(m.errors))
+	for k := range m.errors {
+		errKeys = append(errKeys, k)
+	}
+	sort.Slice(errKeys, func(i, j int) bool {
+		if errKeys[i][0] != errKeys[j][0] {
+			return errKeys[i][0] < errKeys[j][0]
+		}
+		return errKeys[i][1] < errKeys[j][1]
+	})
+	for _, k := range errKeys {
+		sample(w, "webostv_request_errors_total", labels("uri", k[0], "code", k[1]), float64(m.errors[k]))
+	}
+
+	header(w, "webostv_request_duration_seconds", "histogram", "Duration of ssap requests.")
+	uris := make([]string, 0, len(m.durations))
+	for uri := range m.durations {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		h := m.durations[uri]
+		var cumulative uint64
+		for i, le := range requestBuckets {
+			cumulative += h.counts[i]
+			sample(w, "webostv_request_duration_seconds_bucket",
+				labels("uri", uri, "le", formatValue(le)), float64(cumulative))
+		}
+		sample(w, "webostv_request_duration_seconds_bucket", labels("uri", uri, "le", "+Inf"), float64(h.count))
+		sample(w, "webostv_request_duration_seconds_sum", labels("uri", uri), h.sum)
+		sample(w, "webostv_request_duration_seconds_count", labels("uri", uri), float64(h.count))
+	}
+
+	header(w, "webostv_subscription_messages_total", "counter", "Number of messages received on subscriptions.")
+	for _, uri := range sortedKeys(m.subMessages) {
+		sample(w, "webostv_subscription_messages_total", labels("uri", uri), float64(m.subMessages[uri]))
+	}
+	header(w, "webostv_subscription_messages_dropped_total", "counter",
+		"Number of subscription messages dropped because of a slow subscriber.")
+	for _, uri := range sortedKeys(m.subDropped) {
+		sample(w, "webostv_subscription_messages_dropped_total", labels("uri", uri), float64(m.subDropped[uri]))
+	}
+
+	header(w, "webostv_disconnects_total", "counter", "Number of times the connection to the TV was lost.")
+	sample(w, "webostv_disconnects_total", "", float64(m.disconnects))
+	header(w, "webostv_reconnects_total", "counter", "Number of times the connection to the TV was re-established.")
+	sample(w, "webostv_reconnects_total", "", float64(m.reconnects))
+	header(w, "webostv_resume_failures_total", "counter", "Number of times resuming the session failed after reconnecting.")
+	sample(w, "webostv_resume_failures_total", "", float64(m.resumeFailure))
+}
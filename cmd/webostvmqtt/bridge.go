@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type bridge struct {
+	address         string
+	mac             string
+	nodeId          string
+	name            string
+	base            string
+	discoveryPrefix string
+	client          mqtt.Client
+
+	sync.Mutex
+	tv     *webostv.Tv // nil while the TV is not connected
+	power  string      // last published power state, empty if not known
+	inputs []webostv.TvExternalInput
+	apps   []webostv.LaunchPoint
+}
+
+func (b *bridge) topic(name string) string {
+	return b.base + "/" + name
+}
+
+// publish publishes a retained state message. Values other than strings
+// are sent as JSON.
+func (b *bridge) publish(name string, value interface{}) mqtt.Token {
+	var payload []byte
+	switch v := value.(type) {
+	case string:
+		payload = []byte(v)
+	default:
+		var err error
+		payload, err = json.Marshal(v)
+		if err != nil {
+			log.Print("JSON marshal error: ", err)
+			return nil
+		}
+	}
+	return b.client.Publish(b.topic(name), 1, true, payload)
+}
+
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+// onConnect is called whenever the connection to the broker is
+// established. It subscribes to the command topics.
+func (b *bridge) onConnect(client mqtt.Client) {
+	b.publishAvailability()
+	b.Lock()
+	power := b.power
+	b.Unlock()
+	if power != "" {
+		b.publish("power", power)
+	}
+	client.Subscribe(b.topic("+/set"), 1, b.onCommand)
+}
+
+// publishAvailability publishes "online" while the TV is connected. The
+// TV stays available after it has reported turning off, so that it can
+// be turned on again; otherwise losing the connection makes it
+// unavailable because its power state is not known.
+func (b *bridge) publishAvailability() {
+	b.Lock()
+	available := b.tv != nil || b.power == "OFF"
+	b.Unlock()
+	if available {
+		b.publish("availability", "online")
+	} else {
+		b.publish("availability", "offline")
+	}
+}
+
+// setPower publishes the power state if it has changed.
+func (b *bridge) setPower(on bool) {
+	b.Lock()
+	changed := b.power != onOff(on)
+	b.power = onOff(on)
+	b.Unlock()
+	if changed {
+		b.publish("power", onOff(on))
+	}
+}
+
+// run connects to the TV and reconnects whenever the connection is lost,
+// until ctx is done.
+func (b *bridge) run(ctx context.Context) {
+	delay := reconnectMinDelay
+	for {
+		start := time.Now()
+		err := b.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Print("TV connection: ", err)
+		if time.Since(start) > reconnectMaxDelay {
+			delay = reconnectMinDelay
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay *= 2
+		if delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+}
+
+// session connects to the TV and publishes its state until the connection
+// is lost.
+func (b *bridge) session(ctx context.Context) (err error) {
+	tv, err := webostv.DefaultDialer.DialContext(ctx, b.address)
+	if err != nil {
+		return err
+	}
+	defer tv.Close()
+	handlerErr := make(chan error, 1)
+	go func() {
+		handlerErr <- tv.MessageHandler()
+	}()
+
+	result, err := tv.RegisterWithOptions(ctx, webostv.RegisterOptions{})
+	if err != nil {
+		return errors.Wrap(err, "registration failed")
+	}
+	inputs, err := tv.TvGetExternalInputListContext(ctx)
+	if err != nil {
+		log.Print("listing inputs: ", err)
+	}
+	apps, _, err := tv.ApplicationManagerListLaunchPointsContext(ctx)
+	if err != nil {
+		log.Print("listing apps: ", err)
+	}
+
+	b.Lock()
+	b.tv = tv
+	b.inputs = inputs
+	b.apps = apps
+	b.Unlock()
+	defer func() {
+		b.Lock()
+		b.tv = nil
+		b.Unlock()
+		b.publishAvailability()
+	}()
+
+	log.Print("connected to the TV")
+	b.publishAvailability()
+	b.publishDiscovery(ctx, tv, result.UUID)
+
+	mctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	b.startMonitors(mctx, tv)
+
+	select {
+	case err = <-handlerErr:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *bridge) startMonitors(ctx context.Context, tv *webostv.Tv) {
+	monitor := func(name string, f func() error) {
+		go func() {
+			err := f()
+			if err != nil && ctx.Err() == nil {
+				log.Print(name, " monitor: ", err)
+			}
+		}()
+	}
+	monitor("power", func() error {
+		err := tv.PowerMonitorStateContext(ctx, func(ps webostv.PowerState) error {
+			b.setPower(ps.IsOn())
+			return nil
+		})
+		cause := errors.Cause(err)
+		if _, ok := cause.(*webostv.APIError); ok || cause == webostv.ErrUnsupported {
+			// the TV does not report its power state, so it is on
+			// while it is connected
+			b.setPower(true)
+		}
+		return err
+	})
+	monitor("audio", func() error {
+		return tv.AudioMonitorStatusContext(ctx, func(as webostv.AudioStatus) error {
+			b.publish("volume", strconv.Itoa(as.Volume))
+			b.publish("mute", onOff(as.Mute))
+			return nil
+		})
+	})
+	monitor("channel", func() error {
+		return tv.TvMonitorCurrentChannelContext(ctx, func(cur webostv.TvCurrentChannel) error {
+			b.publish("channel", map[string]string{
+				"id":     cur.ChannelId,
+				"number": cur.ChannelNumber,
+				"name":   cur.ChannelName,
+			})
+			return nil
+		})
+	})
+	monitor("app", func() error {
+		return tv.ApplicationManagerMonitorForegroundAppInfoContext(ctx, func(info webostv.ForegroundAppInfo) error {
+			b.publish("app", info.AppId)
+			b.publish("source", b.sourceName(info.AppId))
+			return nil
+		})
+	})
+}
+
+// sourceName returns the label of the input or the title of the app with
+// the given app id.
+func (b *bridge) sourceName(appId string) string {
+	b.Lock()
+	defer b.Unlock()
+	for _, in := range b.inputs {
+		if in.AppId == appId {
+			return in.Label
+		}
+	}
+	for _, app := range b.apps {
+		if app.Id == appId {
+			return app.Title
+		}
+	}
+	return appId
+}
+
+// sourceList returns the labels of the inputs and the titles of the apps.
+func (b *bridge) sourceList() (list []string) {
+	b.Lock()
+	defer b.Unlock()
+	for _, in := range b.inputs {
+		list = append(list, in.Label)
+	}
+	for _, app := range b.apps {
+		list = append(list, app.Title)
+	}
+	return list
+}
+
+// publishDiscovery publishes the Home Assistant MQTT discovery config of
+// the media_player entity.
+func (b *bridge) publishDiscovery(ctx context.Context, tv *webostv.Tv, uuid string) {
+	if b.discoveryPrefix == "" {
+		return
+	}
+	if uuid == "" {
+		uuid = b.nodeId
+	}
+	device := map[string]interface{}{
+		"identifiers":  []string{uuid},
+		"manufacturer": "LG",
+		"name":         b.name,
+	}
+	if info, err := tv.SystemGetSystemInfoContext(ctx); err == nil {
+		device["model"] = info.ModelName
+	}
+	config := map[string]interface{}{
+		"name":                  b.name,
+		"unique_id":             "webostv_" + uuid,
+		"device":                device,
+		"availability_topic":    b.topic("availability"),
+		"state_topic":           b.topic("power"),
+		"command_topic":         b.topic("power/set"),
+		"payload_on":            "ON",
+		"payload_off":           "OFF",
+		"volume_level_topic":    b.topic("volume"),
+		"volume_set_topic":      b.topic("volume/set"),
+		"volume_max":            100,
+		"is_volume_muted_topic": b.topic("mute"),
+		"volume_mute_topic":     b.topic("mute/set"),
+		"source_topic":          b.topic("source"),
+		"select_source_topic":   b.topic("source/set"),
+		"source_list":           b.sourceList(),
+		"media_title_topic":     b.topic("source"),
+	}
+	buf, err := json.Marshal(config)
+	if err != nil {
+		log.Print("JSON marshal error: ", err)
+		return
+	}
+	b.client.Publish(b.discoveryPrefix+"/media_player/"+b.nodeId+"/config", 1, true, buf)
+}
+
+// onCommand handles the messages published to the command topics.
+func (b *bridge) onCommand(client mqtt.Client, msg mqtt.Message) {
+	name := strings.TrimSuffix(strings.TrimPrefix(msg.Topic(), b.base+"/"), "/set")
+	arg := strings.TrimSpace(string(msg.Payload()))
+	go func() {
+		err := b.command(name, arg)
+		if err != nil {
+			log.Print("command ", name, " ", arg, ": ", err)
+		}
+	}()
+}
+
+func parseOnOff(arg string) (on bool, err error) {
+	switch strings.ToUpper(arg) {
+	case "ON", "TRUE", "1":
+		return true, nil
+	case "OFF", "FALSE", "0":
+		return false, nil
+	}
+	return false, errors.New("expected ON or OFF")
+}
+
+func (b *bridge) command(name, arg string) (err error) {
+	b.Lock()
+	tv, power := b.tv, b.power
+	b.Unlock()
+
+	if name == "power" {
+		on, err := parseOnOff(arg)
+		if err != nil {
+			return err
+		}
+		switch {
+		case on && (tv == nil || power == "OFF"):
+			// the TV may stay connected in standby
+			mac := b.mac
+			if mac == "" {
+				mac, err = webostv.DefaultDialer.KnownMAC(b.address)
+				if err != nil {
+					return err
+				}
+			}
+			if mac == "" {
+				return errors.New("MAC address of the TV is not known, use --mac")
+			}
+			return webostv.WakeOnLAN(mac, "")
+		case !on && tv != nil:
+			return tv.SystemTurnOff()
+		}
+		return nil
+	}
+	if tv == nil {
+		return errors.New("TV is not connected")
+	}
+
+	switch name {
+	case "volume":
+		switch arg {
+		case "up":
+			return tv.AudioVolumeUp()
+		case "down":
+			return tv.AudioVolumeDown()
+		}
+		volume, err := strconv.Atoi(arg)
+		if err != nil {
+			// Home Assistant sends fractions if volume_max is not honoured
+			f, err2 := strconv.ParseFloat(arg, 64)
+			if err2 != nil || f < 0 || f > 1 {
+				return errors.New("invalid volume")
+			}
+			volume = int(f*100 + 0.5)
+		}
+		return tv.AudioSetVolume(volume)
+	case "mute":
+		mute, err := parseOnOff(arg)
+		if err != nil {
+			return err
+		}
+		return tv.AudioSetMute(mute)
+	case "channel":
+		return tv.TvOpenChannelId(arg)
+	case "channel_number":
+		return tv.TvOpenChannelNumber(arg)
+	case "input":
+		return tv.TvSwitchInput(arg)
+	case "app":
+		_, err = tv.ApplicationManagerLaunch(arg, nil)
+		return err
+	case "source":
+		return b.selectSource(tv, arg)
+	case "button":
+		btn, err := webostv.ParseButton(arg)
+		if err != nil {
+			return err
+		}
+		return tv.Pointer().Press(btn)
+	case "toast":
+		_, err = tv.SystemNotificationsCreateToast(arg)
+		return err
+	}
+	return errors.New("unknown command")
+}
+
+// selectSource switches to the input with the given label or launches the
+// app with the given title.
+func (b *bridge) selectSource(tv *webostv.Tv, source string) (err error) {
+	b.Lock()
+	inputs, apps := b.inputs, b.apps
+	b.Unlock()
+
+	for _, in := range inputs {
+		if strings.EqualFold(in.Label, source) {
+			return tv.TvSwitchInput(in.Id)
+		}
+	}
+	for _, app := range apps {
+		if strings.EqualFold(app.Title, source) {
+			_, err = tv.ApplicationManagerLaunch(app.Id, nil)
+			return err
+		}
+	}
+	return errors.New("unknown source")
+}
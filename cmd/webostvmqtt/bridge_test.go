@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClient is an mqtt.Client which records the published messages.
+type fakeClient struct {
+	mqtt.Client // not implemented methods panic
+
+	sync.Mutex
+	retained map[string]string // last payload of each topic
+	handlers map[string]mqtt.MessageHandler
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		retained: make(map[string]string),
+		handlers: make(map[string]mqtt.MessageHandler),
+	}
+}
+
+func (c *fakeClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	var s string
+	switch p := payload.(type) {
+	case string:
+		s = p
+	case []byte:
+		s = string(p)
+	}
+	c.Lock()
+	c.retained[topic] = s
+	c.Unlock()
+	return fakeToken{}
+}
+
+func (c *fakeClient) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	c.Lock()
+	c.handlers[topic] = callback
+	c.Unlock()
+	return fakeToken{}
+}
+
+// wait waits until the last payload of topic satisfies ok.
+func (c *fakeClient) wait(t *testing.T, topic string, ok func(payload string) bool) string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		c.Lock()
+		payload, found := c.retained[topic]
+		c.Unlock()
+		if found && ok(payload) {
+			return payload
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: got %q", topic, payload)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func (c *fakeClient) waitFor(t *testing.T, topic, expected string) {
+	t.Helper()
+	c.wait(t, topic, func(payload string) bool { return payload == expected })
+}
+
+type fakeToken struct{}
+
+func (fakeToken) Wait() bool                     { return true }
+func (fakeToken) WaitTimeout(time.Duration) bool { return true }
+func (fakeToken) Error() error                   { return nil }
+
+type fakeMessage struct {
+	mqtt.Message
+	topic   string
+	payload string
+}
+
+func (m fakeMessage) Topic() string   { return m.topic }
+func (m fakeMessage) Payload() []byte { return []byte(m.payload) }
+
+func TestBridge(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse(webostv.UriPowerGetState, webostv.Payload{"state": "Active"})
+	s.HandleResponse(webostv.UriAudioGetStatus, webostv.Payload{"volume": 5, "mute": false})
+	s.HandleResponse(webostv.UriTvGetCurrentChannel, webostv.Payload{
+		"channelId": "1_2_3", "channelNumber": "7", "channelName": "News",
+	})
+	s.HandleResponse(webostv.UriApplicationManagerGetForegroundAppInfo, webostv.Payload{"appId": "com.webos.app.hdmi1"})
+	s.HandleResponse(webostv.UriTvGetExternalInputList, webostv.Payload{
+		"devices": []webostv.Payload{{"id": "HDMI_1", "label": "PS4", "appId": "com.webos.app.hdmi1"}},
+	})
+	s.HandleResponse(webostv.UriApplicationManagerListLaunchPoints, webostv.Payload{
+		"launchPoints": []webostv.Payload{{"id": "netflix", "title": "Netflix"}},
+	})
+	s.HandleResponse(webostv.UriSystemGetSystemInfo, webostv.Payload{"modelName": "OLED55C9"})
+
+	requests := make(chan webostv.Msg, 10)
+	for _, uri := range []string{webostv.UriAudioSetVolume, webostv.UriTvSwitchInput, webostv.UriApplicationManagerLaunch} {
+		uri := uri
+		s.Handle(uri, func(req webostv.Payload) (webostv.Payload, error) {
+			requests <- webostv.Msg{Uri: uri, Payload: req}
+			return nil, nil
+		})
+	}
+
+	defer func(d webostv.Dialer) { webostv.DefaultDialer = d }(webostv.DefaultDialer)
+	webostv.DefaultDialer = *s.Dialer()
+	webostv.DefaultDialer.KeyStore = webostv.NewMemoryKeyStore()
+
+	c := newFakeClient()
+	b := &bridge{
+		address:         s.Address,
+		nodeId:          "lgtv",
+		name:            "LG TV",
+		base:            "webostv/lgtv",
+		discoveryPrefix: "homeassistant",
+		client:          c,
+	}
+	b.onConnect(c)
+	c.waitFor(t, "webostv/lgtv/availability", "offline")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.run(ctx)
+		close(done)
+	}()
+
+	// state publishing
+	c.waitFor(t, "webostv/lgtv/availability", "online")
+	c.waitFor(t, "webostv/lgtv/power", "ON")
+	c.waitFor(t, "webostv/lgtv/volume", "5")
+	c.waitFor(t, "webostv/lgtv/mute", "OFF")
+	c.waitFor(t, "webostv/lgtv/channel", `{"id":"1_2_3","name":"News","number":"7"}`)
+	c.waitFor(t, "webostv/lgtv/app", "com.webos.app.hdmi1")
+	c.waitFor(t, "webostv/lgtv/source", "PS4")
+
+	// discovery
+	payload := c.wait(t, "homeassistant/media_player/lgtv/config", func(string) bool { return true })
+	var config struct {
+		UniqueId     string   `json:"unique_id"`
+		CommandTopic string   `json:"command_topic"`
+		SourceList   []string `json:"source_list"`
+		Device       struct {
+			Identifiers []string
+			Model       string
+		}
+	}
+	err := json.Unmarshal([]byte(payload), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.UniqueId != "webostv_"+s.UUID || config.CommandTopic != "webostv/lgtv/power/set" ||
+		len(config.SourceList) != 2 || config.SourceList[0] != "PS4" || config.SourceList[1] != "Netflix" ||
+		config.Device.Model != "OLED55C9" || len(config.Device.Identifiers) != 1 {
+		t.Errorf("unexpected discovery config %s", payload)
+	}
+
+	// commands
+	c.Lock()
+	onCommand := c.handlers["webostv/lgtv/+/set"]
+	c.Unlock()
+	if onCommand == nil {
+		t.Fatal("command topics not subscribed")
+	}
+	for _, test := range []struct {
+		topic, payload string
+		uri, key       string
+		value          interface{}
+	}{
+		{"volume", "12", webostv.UriAudioSetVolume, "volume", float64(12)},
+		{"volume", "0.3", webostv.UriAudioSetVolume, "volume", float64(30)},
+		{"input", "HDMI_2", webostv.UriTvSwitchInput, "inputId", "HDMI_2"},
+		{"source", "ps4", webostv.UriTvSwitchInput, "inputId", "HDMI_1"},
+		{"source", "Netflix", webostv.UriApplicationManagerLaunch, "id", "netflix"},
+		{"app", "youtube.leanback.v4", webostv.UriApplicationManagerLaunch, "id", "youtube.leanback.v4"},
+	} {
+		onCommand(c, fakeMessage{topic: "webostv/lgtv/" + test.topic + "/set", payload: test.payload})
+		select {
+		case req := <-requests:
+			if req.Uri != test.uri || req.Payload[test.key] != test.value {
+				t.Errorf("%s %s: unexpected request %s %v", test.topic, test.payload, req.Uri, req.Payload)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("%s %s: no request", test.topic, test.payload)
+		}
+	}
+	for _, test := range [][2]string{{"volume", "loud"}, {"source", "VCR"}, {"foo", "bar"}} {
+		if err := b.command(test[0], test[1]); err == nil {
+			t.Errorf("%s %s: expected error", test[0], test[1])
+		}
+	}
+
+	// standby with the connection up
+	s.Publish(webostv.UriPowerGetState, webostv.Payload{"state": "Active Standby"})
+	c.waitFor(t, "webostv/lgtv/power", "OFF")
+	if err := b.command("power", "ON"); err == nil {
+		t.Error("expected error turning on without MAC address")
+	}
+	s.Publish(webostv.UriPowerGetState, webostv.Payload{"state": "Active"})
+	c.waitFor(t, "webostv/lgtv/power", "ON")
+
+	// losing the connection while the TV is on makes it unavailable
+	s.DropConnections()
+	c.waitFor(t, "webostv/lgtv/availability", "offline")
+	c.waitFor(t, "webostv/lgtv/availability", "online")
+
+	// the TV stays available after it has turned off
+	s.Publish(webostv.UriPowerGetState, webostv.Payload{"state": "Suspend", "processing": "Request Power Off"})
+	c.waitFor(t, "webostv/lgtv/power", "OFF")
+	cancel()
+	<-done
+	c.waitFor(t, "webostv/lgtv/availability", "online")
+	c.waitFor(t, "webostv/lgtv/power", "OFF")
+}
+
+func TestBridgeWithoutPowerState(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	defer func(d webostv.Dialer) { webostv.DefaultDialer = d }(webostv.DefaultDialer)
+	webostv.DefaultDialer = *s.Dialer()
+
+	c := newFakeClient()
+	b := &bridge{
+		address: s.Address,
+		nodeId:  "lgtv",
+		base:    "webostv/lgtv",
+		client:  c,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.run(ctx)
+		close(done)
+	}()
+	// the power state is not known, so the TV is on while connected
+	c.waitFor(t, "webostv/lgtv/power", "ON")
+	c.waitFor(t, "webostv/lgtv/availability", "online")
+	cancel()
+	<-done
+	c.waitFor(t, "webostv/lgtv/availability", "offline")
+}
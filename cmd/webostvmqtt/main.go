@@ -0,0 +1,103 @@
+// Command webostvmqtt bridges an LG WebOS TV to an MQTT broker. The state
+// of the TV is published to retained topics and commands are accepted on
+// command topics. A Home Assistant MQTT discovery config for a
+// media_player entity is published when the TV is connected.
+//
+// The TV is available while it is connected, and after it has reported
+// turning off so that it can be turned on with Wake-on-LAN. The power
+// state is "OFF" also when the TV is in standby or its screen is off.
+//
+// Topics, relative to the base topic (default "webostv/lgtv"):
+//
+//	availability   "online" / "offline" (state of the connection to the TV)
+//	power          "ON" / "OFF" (power state reported by the TV)
+//	volume         0-100
+//	mute           "ON" / "OFF"
+//	channel        {"id": ..., "number": ..., "name": ...}
+//	app            id of the foreground app
+//	source         label of the input or title of the app in the foreground
+//
+// Commands are published to "<topic>/set": power (ON/OFF), volume (0-100
+// or "up"/"down"), mute (ON/OFF), channel (channel id), channel_number,
+// input (input id), app (app id), source (input label or app title),
+// button (remote control button name) and toast (message).
+package main
+
+import (
+	"context"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/ogier/pflag"
+	"github.com/snabb/webostv"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const DefaultAddress = "LGsmartTV.lan"
+
+const (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = time.Minute
+)
+
+func main() {
+	address := pflag.StringP("address", "a", DefaultAddress, "name or IP address of the TV")
+	mac := pflag.String("mac", "", "MAC address of the TV for turning it on with Wake-on-LAN if it is not remembered")
+	broker := pflag.StringP("broker", "b", "tcp://localhost:1883", "MQTT broker URL")
+	username := pflag.StringP("username", "u", "", "MQTT user name")
+	password := pflag.String("password", os.Getenv("MQTT_PASSWORD"), "MQTT password (env MQTT_PASSWORD)")
+	nodeId := pflag.String("node-id", "lgtv", "identifier of the TV in topics and discovery")
+	name := pflag.String("name", "LG TV", "name of the Home Assistant entity")
+	topic := pflag.String("topic", "", "base topic (default \"webostv/NODE-ID\")")
+	discoveryPrefix := pflag.String("discovery-prefix", "homeassistant",
+		"Home Assistant discovery prefix, empty to disable discovery")
+	pflag.Parse()
+
+	ks, err := webostv.NewFileKeyStore("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	webostv.DefaultDialer.KeyStore = ks
+
+	b := &bridge{
+		address:         *address,
+		mac:             *mac,
+		nodeId:          *nodeId,
+		name:            *name,
+		base:            *topic,
+		discoveryPrefix: *discoveryPrefix,
+	}
+	if b.base == "" {
+		b.base = "webostv/" + b.nodeId
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(*broker).
+		SetClientID("webostvmqtt-"+b.nodeId).
+		SetUsername(*username).
+		SetPassword(*password).
+		SetAutoReconnect(true).
+		SetWill(b.topic("availability"), "offline", 1, true).
+		SetOnConnectHandler(b.onConnect)
+	b.client = mqtt.NewClient(opts)
+	token := b.client.Connect()
+	token.Wait()
+	if token.Error() != nil {
+		log.Fatal("MQTT connection error: ", token.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		<-sigCh
+		cancel()
+	}()
+
+	b.run(ctx)
+
+	b.publish("availability", "offline").Wait()
+	b.client.Disconnect(1000)
+}
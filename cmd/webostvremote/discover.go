@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv/discovery"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const discoverTimeout = 3 * time.Second
+
+// discoverAddress searches for TVs on the local network and lets the user
+// choose one of them if more than one is found.
+func discoverAddress() (address string, err error) {
+	fmt.Fprintln(os.Stderr, "Searching for TVs...")
+	tvs, err := discovery.Discover(context.Background(), discoverTimeout)
+	if err != nil {
+		return "", errors.Wrap(err, "discovery failed")
+	}
+	switch len(tvs) {
+	case 0:
+		return "", errors.New("no TVs found")
+	case 1:
+		fmt.Fprintln(os.Stderr, "Found", describeTv(tvs[0]))
+		return tvs[0].Address, nil
+	}
+
+	for i, tv := range tvs {
+		fmt.Fprintf(os.Stderr, "%d) %s\n", i+1, describeTv(tv))
+	}
+	in := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprintf(os.Stderr, "Select TV [1-%d]: ", len(tvs))
+		line, err := in.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(line))
+		if err == nil && n >= 1 && n <= len(tvs) {
+			return tvs[n-1].Address, nil
+		}
+	}
+}
+
+func describeTv(tv discovery.Tv) string {
+	name := tv.FriendlyName
+	if name == "" {
+		name = "unknown"
+	}
+	if tv.ModelName != "" {
+		name += " (" + tv.ModelName + ")"
+	}
+	return name + " at " + tv.Address
+}
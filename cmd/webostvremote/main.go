@@ -1,21 +1,27 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"github.com/gdamore/tcell"
 	"github.com/inconshreveable/log15"
 	"github.com/ogier/pflag"
+	"github.com/pkg/errors"
 	"github.com/rivo/tview"
 	"github.com/snabb/webostv"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
 
 const DefaultAddress = "LGsmartTV.lan"
 
+const powerOnTimeout = 60 * time.Second
+
 type myError struct {
 	where string
 	err   error
@@ -39,6 +45,9 @@ type myApp struct {
 	wInputs   *inputs
 	wApps     *apps
 	nextFocus map[tview.Primitive]tview.Primitive
+	// focusOrder lists the focusable widgets which are shown, in the
+	// order of moving the focus with TAB
+	focusOrder []tview.Primitive
 
 	logger log15.Logger
 }
@@ -87,8 +96,8 @@ func (app *myApp) inputCapture(event *tcell.EventKey) *tcell.EventKey {
 	case tcell.KeyTAB:
 		if nf, ok := app.nextFocus[currentFocus]; ok {
 			app.changeFocus(currentFocus, nf)
-		} else {
-			app.changeFocus(currentFocus, app.wChannels)
+		} else if len(app.focusOrder) > 0 {
+			app.changeFocus(currentFocus, app.focusOrder[0])
 		}
 		return nil
 	case tcell.KeyBacktab:
@@ -98,7 +107,9 @@ func (app *myApp) inputCapture(event *tcell.EventKey) *tcell.EventKey {
 				return nil
 			}
 		}
-		app.changeFocus(currentFocus, app.wVolume)
+		if len(app.focusOrder) > 0 {
+			app.changeFocus(currentFocus, app.focusOrder[len(app.focusOrder)-1])
+		}
 		return nil
 	case tcell.KeyExit, tcell.KeyESC:
 		app.Stop()
@@ -119,16 +130,16 @@ func (app *myApp) inputCapture(event *tcell.EventKey) *tcell.EventKey {
 	case tcell.KeyRune:
 		switch event.Rune() {
 		case 'v', 'V':
-			app.changeFocus(currentFocus, app.wVolume)
+			app.focusIfShown(currentFocus, app.wVolume)
 			return nil
 		case 'c', 'C':
-			app.changeFocus(currentFocus, app.wChannels)
+			app.focusIfShown(currentFocus, app.wChannels)
 			return nil
 		case 'i', 'I':
-			app.changeFocus(currentFocus, app.wInputs)
+			app.focusIfShown(currentFocus, app.wInputs)
 			return nil
 		case 'a', 'A':
-			app.changeFocus(currentFocus, app.wApps)
+			app.focusIfShown(currentFocus, app.wApps)
 			return nil
 		case 'q', 'Q':
 			app.Stop()
@@ -156,21 +167,52 @@ func (app *myApp) initWidgets() {
 	app.wApps.updateInfo = app.wSelInfo.update
 }
 
+// shown reports whether widget is shown. The widgets for services which
+// the TV does not support are hidden.
+func (app *myApp) shown(widget tview.Primitive) bool {
+	_, ok := app.nextFocus[widget]
+	return ok
+}
+
+func (app *myApp) focusIfShown(currentFocus, widget tview.Primitive) {
+	if app.shown(widget) {
+		app.changeFocus(currentFocus, widget)
+	}
+}
+
 func (app *myApp) initLayout() {
+	showVolume := tv.Supports(webostv.UriAudioGetStatus)
+	showChannels := tv.Supports(webostv.UriTvGetChannelList)
+	showInputs := tv.Supports(webostv.UriTvGetExternalInputList)
+	showApps := tv.Supports(webostv.UriApplicationManagerListLaunchPoints)
+
 	layoutLeft := tview.NewFlex().
 		SetDirection(tview.FlexRow).
-		AddItem(app.wTvInfo, 0, 2, false).
-		AddItem(app.wVolume, 3, 0, false).
-		AddItem(app.wHelp, 0, 2, false)
+		AddItem(app.wTvInfo, 0, 2, false)
+	if showVolume {
+		layoutLeft.AddItem(app.wVolume, 3, 0, false)
+	}
+	layoutLeft.AddItem(app.wHelp, 0, 2, false)
+
+	layoutLists := tview.NewFlex()
+	if showChannels {
+		layoutLists.AddItem(app.wChannels, 0, 4, false)
+	}
+	if showInputs || showApps {
+		layoutInputsApps := tview.NewFlex().
+			SetDirection(tview.FlexRow)
+		if showInputs {
+			layoutInputsApps.AddItem(app.wInputs, 0, 1, false)
+		}
+		if showApps {
+			layoutInputsApps.AddItem(app.wApps, 0, 2, false)
+		}
+		layoutLists.AddItem(layoutInputsApps, 0, 3, false)
+	}
 
 	layoutRight := tview.NewFlex().
 		SetDirection(tview.FlexRow).
-		AddItem(tview.NewFlex().
-			AddItem(app.wChannels, 0, 4, false).
-			AddItem(tview.NewFlex().
-				SetDirection(tview.FlexRow).
-				AddItem(app.wInputs, 0, 1, false).
-				AddItem(app.wApps, 0, 2, false), 0, 3, false), 0, 3, false).
+		AddItem(layoutLists, 0, 3, false).
 		AddItem(app.wSelInfo, 0, 1, false)
 
 	layout := tview.NewFlex().
@@ -179,19 +221,81 @@ func (app *myApp) initLayout() {
 
 	app.SetRoot(layout, true)
 
-	app.nextFocus = map[tview.Primitive]tview.Primitive{
-		app.wChannels: app.wInputs,
-		app.wInputs:   app.wApps,
-		app.wApps:     app.wVolume,
-		app.wVolume:   app.wChannels,
+	for _, w := range []struct {
+		widget tview.Primitive
+		show   bool
+	}{
+		{app.wChannels, showChannels},
+		{app.wInputs, showInputs},
+		{app.wApps, showApps},
+		{app.wVolume, showVolume},
+	} {
+		if w.show {
+			app.focusOrder = append(app.focusOrder, w.widget)
+		}
+	}
+	app.nextFocus = make(map[tview.Primitive]tview.Primitive)
+	for i, w := range app.focusOrder {
+		app.nextFocus[w] = app.focusOrder[(i+1)%len(app.focusOrder)]
 	}
 }
 
-func initTv(address string) {
+// macStoreKey returns the store key for the MAC address of the TV. The
+// MAC address is now kept in the key store, older versions stored it here.
+func macStoreKey(address string) string {
+	return "mac/" + address
+}
+
+// uuidStoreKey returns the store key for the UUID of the TV. The client
+// key and the certificate fingerprint are kept in the key store by UUID.
+// Older versions stored the client key with the plain address as the key.
+func uuidStoreKey(address string) string {
+	return "uuid/" + address
+}
+
+// readPin asks the user for the PIN displayed on the TV.
+func readPin(ctx context.Context) (pin string, err error) {
+	fmt.Fprint(os.Stderr, "Enter the PIN shown on the TV: ")
+	pin, err = bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(pin), err
+}
+
+func initTv(address string, wake, pin bool) {
 	store := openMyStore()
-	clientKey := store.Get(address)
+	mac := store.Get(macStoreKey(address))
+	ks := openKeyStore()
+	webostv.DefaultDialer.KeyStore = ks
+
+	var clientKey string
+	if uuid := store.Get(uuidStoreKey(address)); uuid != "" {
+		cred, err := ks.Load(uuid)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		clientKey = cred.ClientKey
+	}
+	legacyKey := store.Get(address)
+	if clientKey == "" {
+		clientKey = legacyKey
+	}
 
 	var err error
+	if wake {
+		fmt.Fprintln(os.Stderr, "Turning on the TV...")
+		ctx, cancel := context.WithTimeout(context.Background(), powerOnTimeout)
+		err = webostv.DefaultDialer.PowerOn(ctx, address, mac, "", clientKey)
+		cancel()
+		if errors.Cause(err) == webostv.ErrUnknownMAC {
+			fmt.Fprintln(os.Stderr, "MAC address of the TV is not known, connect once while the TV is on")
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "TV power on error:", err)
+			os.Exit(1)
+		}
+	}
+
 	tv.Tv, err = webostv.DefaultDialer.Dial(address)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "TV connection error:", err)
@@ -205,15 +309,38 @@ func initTv(address string) {
 		app.Stop()
 	}()
 
-	newKey, err := tv.Register(clientKey)
+	opts := webostv.RegisterOptions{
+		ClientKey:          clientKey,
+		DetectCapabilities: true,
+	}
+	if pin {
+		opts.PairingType = webostv.PairingTypePin
+		opts.PinFunc = readPin
+	}
+	result, err := tv.RegisterWithOptions(context.Background(), opts)
 	if err != nil {
 		tv.Close()
 		fmt.Fprintln(os.Stderr, "TV registration error:", err)
+		if webostv.IsCertificateMismatch(err) {
+			fmt.Fprintln(os.Stderr, "If the certificate of the TV has legitimately changed, remove the TV from "+
+				ks.Path+".")
+		}
 		os.Exit(1)
 	}
 
-	if newKey != clientKey {
-		store.Set(address, newKey)
+	store.Set(uuidStoreKey(address), result.UUID)
+	if legacyKey != "" {
+		store.Delete(address)
+	}
+	if mac != "" {
+		cred, err := ks.Load(result.UUID)
+		if err == nil && cred.MAC == "" {
+			cred.MAC = mac
+			err = ks.Save(result.UUID, cred)
+		}
+		if err == nil {
+			store.Delete(macStoreKey(address))
+		}
 	}
 	store.Close()
 }
@@ -229,6 +356,9 @@ func main() {
 	}
 
 	debugLog := pflag.StringP("debug", "d", "", "debug log file name")
+	discover := pflag.Bool("discover", false, "search for TVs on the local network")
+	wake := pflag.BoolP("wake", "w", false, "turn on the TV with Wake-on-LAN")
+	pin := pflag.Bool("pin", false, "pair with a PIN shown on the TV instead of a prompt")
 	pflag.Parse()
 
 	if *debugLog != "" {
@@ -243,10 +373,16 @@ func main() {
 	}
 
 	var address string
-	switch pflag.NArg() {
-	case 0:
+	switch {
+	case *discover && pflag.NArg() == 0:
+		address, err = discoverAddress()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			os.Exit(1)
+		}
+	case pflag.NArg() == 0:
 		address = DefaultAddress
-	case 1:
+	case pflag.NArg() == 1 && !*discover:
 		address = pflag.Arg(0)
 	default:
 		pflag.Usage()
@@ -257,7 +393,7 @@ func main() {
 
 	rand.Seed(time.Now().UnixNano())
 
-	initTv(address)
+	initTv(address, *wake, *pin)
 
 	if *debugLog != "" {
 		tv.SetDebug(func(str string) {
@@ -272,16 +408,18 @@ func main() {
 	var wg sync.WaitGroup
 	quit := make(chan struct{})
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		err := tv.AudioMonitorStatus(func(as webostv.AudioStatus) error {
-			app.wVolume.update(as.Volume)
-			return nil
-		}, quit)
-		tv.errorCh <- myError{"AudioMonitorStatus", err}
-		app.Stop()
-	}()
+	if app.shown(app.wVolume) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := tv.AudioMonitorStatus(func(as webostv.AudioStatus) error {
+				app.wVolume.update(as.Volume)
+				return nil
+			}, quit)
+			tv.errorCh <- myError{"AudioMonitorStatus", err}
+			app.Stop()
+		}()
+	}
 
 	wg.Add(1)
 	go func() {
@@ -296,12 +434,18 @@ func main() {
 		defer wg.Done()
 		app.wTvInfo.updateFromTv()
 		app.Draw()
-		app.wChannels.updateFromTv()
-		app.Draw()
-		app.wInputs.updateFromTv()
-		app.Draw()
-		app.wApps.updateFromTv()
-		app.Draw()
+		if app.shown(app.wChannels) {
+			app.wChannels.updateFromTv()
+			app.Draw()
+		}
+		if app.shown(app.wInputs) {
+			app.wInputs.updateFromTv()
+			app.Draw()
+		}
+		if app.shown(app.wApps) {
+			app.wApps.updateFromTv()
+			app.Draw()
+		}
 		// XXX check errors ?
 	}()
 
@@ -340,6 +484,15 @@ errorChReadLoop:
 	}
 }
 
+func openKeyStore() (ks *webostv.FileKeyStore) {
+	ks, err := webostv.NewFileKeyStore("")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return ks
+}
+
 func openMyStore() (store *Store) {
 	var name string
 	if home := os.Getenv("HOME"); home != "" {
@@ -76,6 +76,14 @@ func (st *Store) Set(key, value string) (err error) {
 	return st.writeAll()
 }
 
+func (st *Store) Delete(key string) (err error) {
+	if _, ok := st.data[key]; !ok {
+		return nil
+	}
+	delete(st.data, key)
+	return st.writeAll()
+}
+
 func (st *Store) Close() (err error) {
 	err = st.file.Close()
 	st.data = nil
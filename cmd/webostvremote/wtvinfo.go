@@ -110,9 +110,9 @@ func (i *tvInfo) monitorTvCurrentInfo(quit chan struct{}) (err error) {
 		var startChannelMonitor, stopChannelMonitor bool
 		i.Lock()
 		if info.IsLiveTv() && !i.foregroundAppInfo.IsLiveTv() {
-			startChannelMonitor = true
+			startChannelMonitor = tv.Supports(webostv.UriTvGetCurrentChannel)
 		} else if !info.IsLiveTv() && i.foregroundAppInfo.IsLiveTv() {
-			stopChannelMonitor = true
+			stopChannelMonitor = channelQuitCh != nil
 		}
 		i.foregroundAppInfo = info
 		i.update()
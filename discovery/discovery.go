@@ -0,0 +1,216 @@
+// Package discovery finds LG WebOS TVs on the local network with SSDP.
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SearchTarget = "urn:lge-com:service:webos-second-screen:1"
+
+type Tv struct {
+	Address      string // IP address of the TV, usable with webostv.Dialer.Dial
+	UUID         string // "uuid" part of the USN header
+	FriendlyName string // "friendlyName" from the device description
+	ModelName    string // "modelName" from the device description
+	Location     string // URL of the UPnP device description
+	Server       string // "SERVER" header of the SSDP response
+}
+
+type Discoverer struct {
+	// MulticastAddress is the address where M-SEARCH requests are sent.
+	MulticastAddress string
+	SearchTarget     string
+	// MX is the maximum wait time in seconds for the TVs to respond.
+	MX int
+	// Interval between repeated M-SEARCH requests. SSDP is UDP based and
+	// packets get lost.
+	Interval time.Duration
+	// HTTPClient is used for fetching the device descriptions. If it is nil,
+	// the device descriptions are not fetched.
+	HTTPClient *http.Client
+}
+
+var DefaultDiscoverer = Discoverer{
+	MulticastAddress: "239.255.255.250:1900",
+	SearchTarget:     SearchTarget,
+	MX:               2,
+	Interval:         time.Second,
+	HTTPClient: &http.Client{
+		Timeout: time.Second * 5,
+	},
+}
+
+// Discover searches for TVs with DefaultDiscoverer for the given duration
+// and returns the TVs which were found.
+func Discover(ctx context.Context, timeout time.Duration) (tvs []Tv, err error) {
+	return DefaultDiscoverer.Discover(ctx, timeout)
+}
+
+// Discover searches for TVs for the given duration and returns the TVs
+// which were found.
+func (d *Discoverer) Discover(ctx context.Context, timeout time.Duration) (tvs []Tv, err error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ch, err := d.Search(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for tv := range ch {
+		tvs = append(tvs, tv)
+	}
+	return tvs, nil
+}
+
+// Search sends M-SEARCH requests until ctx is done. Each TV which responds
+// is sent to the returned channel once. The channel is closed when ctx is
+// done and the pending device descriptions have been fetched or given up,
+// so the caller must read it until it is closed. An error is returned if
+// the first M-SEARCH request can not be sent.
+func (d *Discoverer) Search(ctx context.Context) (<-chan Tv, error) {
+	raddr, err := net.ResolveUDPAddr("udp4", d.MulticastAddress)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req := []byte(fmt.Sprintf("M-SEARCH * HTTP/1.1\r\n"+
+		"HOST: %s\r\n"+
+		"MAN: \"ssdp:discover\"\r\n"+
+		"MX: %d\r\n"+
+		"ST: %s\r\n"+
+		"\r\n", d.MulticastAddress, d.MX, d.SearchTarget))
+
+	_, err = conn.WriteToUDP(req, raddr)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go func() {
+		defer conn.Close()
+		for {
+			select {
+			case <-time.After(d.Interval):
+			case <-ctx.Done():
+				return
+			}
+			// a lost request is not fatal, the previous ones may
+			// still be answered
+			conn.WriteToUDP(req, raddr)
+		}
+	}()
+
+	ch := make(chan Tv)
+	go func() {
+		defer close(ch)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+
+		seen := make(map[string]bool)
+		buf := make([]byte, 2048)
+		for {
+			n, addr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				// the connection is closed when ctx is done
+				return
+			}
+			tv, err := parseResponse(buf[:n], d.SearchTarget)
+			if err != nil {
+				continue
+			}
+			tv.Address = addr.IP.String()
+			key := tv.UUID
+			if key == "" {
+				key = tv.Address
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if d.HTTPClient != nil && tv.Location != "" {
+					d.describe(ctx, &tv)
+				}
+				// the TV is reported even if ctx is done while its
+				// description is fetched
+				ch <- tv
+			}()
+		}
+	}()
+
+	return ch, nil
+}
+
+func parseResponse(buf []byte, st string) (tv Tv, err error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf)), nil)
+	if err != nil {
+		return tv, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return tv, errors.Errorf("unexpected status: %s", resp.Status)
+	}
+	if resp.Header.Get("ST") != st {
+		return tv, errors.Errorf("unexpected search target: %s", resp.Header.Get("ST"))
+	}
+	// "USN: uuid:6fd3c7e4-a0c4-4ee5-b2d6-86a8e2ca3aa7::urn:lge-com:service:webos-second-screen:1"
+	usn := resp.Header.Get("USN")
+	if i := strings.Index(usn, "::"); i >= 0 {
+		usn = usn[:i]
+	}
+	tv.UUID = strings.TrimPrefix(usn, "uuid:")
+	tv.Location = resp.Header.Get("LOCATION")
+	tv.Server = resp.Header.Get("SERVER")
+	return tv, nil
+}
+
+type deviceDescription struct {
+	Device struct {
+		FriendlyName string `xml:"friendlyName"`
+		ModelName    string `xml:"modelName"`
+		UDN          string `xml:"UDN"`
+	} `xml:"device"`
+}
+
+// describe fills in the fields from the UPnP device description. Errors
+// are ignored because the SSDP response is enough for connecting.
+func (d *Discoverer) describe(ctx context.Context, tv *Tv) {
+	req, err := http.NewRequest("GET", tv.Location, nil)
+	if err != nil {
+		return
+	}
+	resp, err := d.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	var desc deviceDescription
+	err = xml.NewDecoder(resp.Body).Decode(&desc)
+	if err != nil {
+		return
+	}
+	tv.FriendlyName = desc.Device.FriendlyName
+	tv.ModelName = desc.Device.ModelName
+	if tv.UUID == "" {
+		tv.UUID = strings.TrimPrefix(desc.Device.UDN, "uuid:")
+	}
+}
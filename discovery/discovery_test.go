@@ -0,0 +1,125 @@
+package discovery_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/snabb/webostv/discovery"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeResponder answers M-SEARCH requests on a local UDP port like TVs
+// would answer the multicast requests.
+type fakeResponder struct {
+	conn      *net.UDPConn
+	responses []string
+}
+
+func newFakeResponder(t *testing.T, responses ...string) *fakeResponder {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeResponder{conn: conn, responses: responses}
+	go r.serve()
+	return r
+}
+
+func (r *fakeResponder) serve() {
+	buf := make([]byte, 2048)
+	for {
+		n, addr, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf[:n])))
+		if err != nil || req.Method != "M-SEARCH" || req.Header.Get("MAN") != `"ssdp:discover"` {
+			continue
+		}
+		for _, resp := range r.responses {
+			r.conn.WriteToUDP([]byte(resp), addr)
+		}
+	}
+}
+
+func (r *fakeResponder) Close() {
+	r.conn.Close()
+}
+
+func ssdpResponse(st, uuid, location string) string {
+	return "HTTP/1.1 200 OK\r\n" +
+		"CACHE-CONTROL: max-age=1800\r\n" +
+		"EXT:\r\n" +
+		"LOCATION: " + location + "\r\n" +
+		"SERVER: WebOS/4.1.0 UPnP/1.0\r\n" +
+		"ST: " + st + "\r\n" +
+		"USN: uuid:" + uuid + "::" + st + "\r\n" +
+		"\r\n"
+}
+
+const description = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <friendlyName>Living room</friendlyName>
+    <modelName>OLED55C9PLA</modelName>
+    <UDN>uuid:%s</UDN>
+  </device>
+</root>`
+
+func TestDiscover(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tv1.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, description, "tv1")
+	})
+	mux.HandleFunc("/tv2.xml", func(w http.ResponseWriter, r *http.Request) {
+		// the description is still being fetched when the search ends
+		<-r.Context().Done()
+	})
+	hs := httptest.NewServer(mux)
+	defer hs.Close()
+
+	r := newFakeResponder(t,
+		ssdpResponse(discovery.SearchTarget, "tv1", hs.URL+"/tv1.xml"),
+		ssdpResponse(discovery.SearchTarget, "tv2", hs.URL+"/tv2.xml"),
+		ssdpResponse("upnp:rootdevice", "router", hs.URL+"/router.xml"),
+	)
+	defer r.Close()
+
+	d := discovery.DefaultDiscoverer
+	d.MulticastAddress = r.conn.LocalAddr().String()
+	d.Interval = time.Millisecond * 50
+	tvs, err := d.Discover(context.Background(), time.Millisecond*300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(tvs, func(i, j int) bool { return tvs[i].UUID < tvs[j].UUID })
+	if len(tvs) != 2 {
+		t.Fatalf("found %d TVs, expected 2: %+v", len(tvs), tvs)
+	}
+	tv := tvs[0]
+	if tv.UUID != "tv1" || tv.Address != "127.0.0.1" || tv.FriendlyName != "Living room" ||
+		tv.ModelName != "OLED55C9PLA" || tv.Server != "WebOS/4.1.0 UPnP/1.0" {
+		t.Errorf("unexpected TV %+v", tv)
+	}
+	tv = tvs[1]
+	if tv.UUID != "tv2" || !strings.HasSuffix(tv.Location, "/tv2.xml") || tv.FriendlyName != "" {
+		t.Errorf("unexpected TV %+v", tv)
+	}
+}
+
+func TestDiscoverSendError(t *testing.T) {
+	d := discovery.DefaultDiscoverer
+	// sending to port zero fails
+	d.MulticastAddress = "127.0.0.1:0"
+	_, err := d.Discover(context.Background(), time.Millisecond*100)
+	if err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,216 @@
+package webostv
+
+//go:generate go run ./internal/genendpoints
+
+// Endpoint describes an ssap API endpoint of the TV. For each Endpoint a
+// constant Uri<Name> is generated and, unless Custom is set, the wrapper
+// methods <Name> and <Name>Context. If Subscribe is set, the Monitor
+// methods are generated too: their names are formed by replacing "Get" in
+// Name with "Monitor".
+//
+// After editing Endpoints, run "go generate" to update endpoints_gen.go.
+type Endpoint struct {
+	Name string // Go name, such as "AudioGetStatus"
+	Uri  string
+
+	// Request lists the parameters of the request as Go parameter
+	// declarations, such as "text string, replace bool". The parameter
+	// names are used as the keys of the request payload.
+	Request string
+	// Response is the Go type of the result. If Field is empty, the whole
+	// response payload is decoded to it, otherwise only the named field
+	// of the payload is. If Response is empty, there is no result.
+	Response string
+	Field    string
+
+	Subscribe  bool   // whether the endpoint can be subscribed to
+	Permission string // permission required in the pairing manifest, if any
+	Custom     bool   // whether the wrapper is written by hand
+}
+
+// Endpoints is the registry of the known ssap endpoints.
+var Endpoints = []Endpoint{
+	// services
+
+	// "payload":{"services":[{"name":"api","version":1},{"name":"audio","version":1},{"name":"media.controls","version":1},{"name":"media.viewer","version":1},{"name":"pairing","version":1},{"name":"system","version":1},{"name":"system.launcher","version":1},{"name":"system.notifications","version":1},{"name":"tv","version":1},{"name":"webapp","version":2}],"returnValue":true}
+	{Name: "ApiGetServiceList", Uri: "ssap://api/getServiceList",
+		Response: "[]ServiceListEntry", Field: "Services"},
+
+	// applications
+
+	{Name: "ApplicationManagerGetAppInfo", Uri: "ssap://com.webos.applicationManager/getAppInfo",
+		Request: "id string", Response: "App", Field: "AppInfo", Permission: "READ_INSTALLED_APPS"},
+	// {"type":"response","id":"CyvqdwSl","payload":{"appId":"com.webos.app.hdmi2","returnValue":true,"windowId":"","processId":"n-1059"}}
+	{Name: "ApplicationManagerGetForegroundAppInfo", Uri: "ssap://com.webos.applicationManager/getForegroundAppInfo",
+		Response: "ForegroundAppInfo", Subscribe: true, Permission: "READ_RUNNING_APPS"},
+	{Name: "ApplicationManagerLaunch", Uri: "ssap://com.webos.applicationManager/launch",
+		Permission: "LAUNCH", Custom: true},
+	{Name: "ApplicationManagerListApps", Uri: "ssap://com.webos.applicationManager/listApps",
+		Response: "[]App", Field: "Apps", Permission: "READ_INSTALLED_APPS"},
+	{Name: "ApplicationManagerListLaunchPoints", Uri: "ssap://com.webos.applicationManager/listLaunchPoints",
+		Permission: "READ_INSTALLED_APPS", Custom: true},
+	{Name: "SystemLauncherClose", Uri: "ssap://system.launcher/close",
+		Request: "sessionId string", Permission: "CLOSE"},
+	{Name: "SystemLauncherGetAppState", Uri: "ssap://system.launcher/getAppState",
+		Permission: "READ_APP_STATUS", Custom: true},
+	{Name: "SystemLauncherLaunch", Uri: "ssap://system.launcher/launch",
+		Permission: "LAUNCH", Custom: true},
+	{Name: "SystemLauncherOpen", Uri: "ssap://system.launcher/open",
+		Permission: "LAUNCH", Custom: true},
+
+	// audio
+
+	// "payload":{"mute":false,"returnValue":true}
+	{Name: "AudioGetMute", Uri: "ssap://audio/getMute",
+		Response: "bool", Field: "Mute", Permission: "CONTROL_AUDIO"},
+	// "payload":{"returnValue":true,"scenario":"mastervolume_tv_speaker","volume":9,"mute":false}
+	{Name: "AudioGetStatus", Uri: "ssap://audio/getStatus",
+		Response: "AudioStatus", Subscribe: true, Permission: "CONTROL_AUDIO"},
+	{Name: "AudioGetVolume", Uri: "ssap://audio/getVolume",
+		Subscribe: true, Permission: "CONTROL_AUDIO", Custom: true},
+	{Name: "AudioSetMute", Uri: "ssap://audio/setMute",
+		Request: "mute bool", Permission: "CONTROL_AUDIO"},
+	{Name: "AudioSetVolume", Uri: "ssap://audio/setVolume",
+		Request: "volume int", Permission: "CONTROL_AUDIO"},
+	{Name: "AudioVolumeDown", Uri: "ssap://audio/volumeDown",
+		Permission: "CONTROL_AUDIO"},
+	{Name: "AudioVolumeUp", Uri: "ssap://audio/volumeUp",
+		Permission: "CONTROL_AUDIO"},
+	// "payload":{"soundOutput":"tv_speaker","returnValue":true}
+	{Name: "AudioGetSoundOutput", Uri: "ssap://com.webos.service.apiadapter/audio/getSoundOutput",
+		Response: "string", Field: "SoundOutput", Subscribe: true, Permission: "CONTROL_AUDIO"},
+	{Name: "AudioChangeSoundOutput", Uri: "ssap://com.webos.service.apiadapter/audio/changeSoundOutput",
+		Request: "output string", Permission: "CONTROL_AUDIO"},
+
+	// media
+
+	{Name: "MediaControlsFastForward", Uri: "ssap://media.controls/fastForward",
+		Permission: "CONTROL_INPUT_MEDIA_PLAYBACK"},
+	{Name: "MediaControlsPause", Uri: "ssap://media.controls/pause",
+		Permission: "CONTROL_INPUT_MEDIA_PLAYBACK"},
+	{Name: "MediaControlsPlay", Uri: "ssap://media.controls/play",
+		Permission: "CONTROL_INPUT_MEDIA_PLAYBACK"},
+	{Name: "MediaControlsRewind", Uri: "ssap://media.controls/rewind",
+		Permission: "CONTROL_INPUT_MEDIA_PLAYBACK"},
+	{Name: "MediaControlsStop", Uri: "ssap://media.controls/stop",
+		Permission: "CONTROL_INPUT_MEDIA_PLAYBACK"},
+	{Name: "MediaViewerClose", Uri: "ssap://media.viewer/close",
+		Request: "sessionId string", Permission: "CLOSE"},
+	{Name: "MediaViewerOpen", Uri: "ssap://media.viewer/open",
+		Permission: "LAUNCH", Custom: true},
+
+	// tv
+
+	{Name: "TvChannelDown", Uri: "ssap://tv/channelDown",
+		Permission: "CONTROL_INPUT_TV"},
+	{Name: "TvChannelUp", Uri: "ssap://tv/channelUp",
+		Permission: "CONTROL_INPUT_TV"},
+	{Name: "TvGetChannelCurrentProgramInfo", Uri: "ssap://tv/getChannelCurrentProgramInfo",
+		Permission: "READ_CURRENT_CHANNEL", Custom: true},
+	{Name: "TvGetChannelList", Uri: "ssap://tv/getChannelList",
+		Response: "[]TvChannel", Field: "ChannelList", Permission: "READ_TV_CHANNEL_LIST"},
+	{Name: "TvGetChannelProgramInfo", Uri: "ssap://tv/getChannelProgramInfo",
+		Permission: "READ_CURRENT_CHANNEL", Custom: true},
+	{Name: "TvGetCurrentChannel", Uri: "ssap://tv/getCurrentChannel",
+		Response: "TvCurrentChannel", Subscribe: true, Permission: "READ_CURRENT_CHANNEL"},
+	{Name: "TvGetExternalInputList", Uri: "ssap://tv/getExternalInputList",
+		Response: "[]TvExternalInput", Field: "Devices", Permission: "READ_INPUT_DEVICE_LIST"},
+	{Name: "TvOpenChannelId", Uri: "ssap://tv/openChannel",
+		Request: "channelId string", Permission: "CONTROL_INPUT_TV"},
+	{Name: "TvOpenChannelNumber", Uri: "ssap://tv/openChannel",
+		Request: "channelNumber string", Permission: "CONTROL_INPUT_TV"},
+	{Name: "TvSwitchInput", Uri: "ssap://tv/switchInput",
+		Request: "inputId string", Permission: "CONTROL_INPUT_TV"},
+
+	// input
+
+	{Name: "ImeDeleteCharacters", Uri: "ssap://com.webos.service.ime/deleteCharacters",
+		Request: "count int", Permission: "CONTROL_INPUT_TEXT"},
+	{Name: "ImeInsertText", Uri: "ssap://com.webos.service.ime/insertText",
+		Request: "text string, replace bool", Permission: "CONTROL_INPUT_TEXT"},
+	// {"type":"response","id":"nlHxhqwT","payload":{"currentWidget":{"autoCapitalizationEnabled":true,"contentType":"text","correctionEnabled":false,"cursorPosition":13,"focus":true,"hasSurroundingText":true,"hiddenText":false,"predictionEnabled":true,"surroundingTextLength":13},"focusChanged":true}}
+	{Name: "ImeSendEnterKey", Uri: "ssap://com.webos.service.ime/sendEnterKey",
+		Permission: "CONTROL_INPUT_TEXT"},
+	{Name: "GetPointerInputSocket", Uri: "ssap://com.webos.service.networkinput/getPointerInputSocket",
+		Response: "string", Field: "SocketPath", Permission: "CONTROL_MOUSE_AND_KEYBOARD"},
+
+	// system
+
+	{Name: "SdxGetHttpHeaderForServiceRequest", Uri: "ssap://com.webos.service.sdx/getHttpHeaderForServiceRequest",
+		Permission: "READ_LGE_SDX", Custom: true},
+	// {"clearedForDuty":true,"returnValue":true}
+	{Name: "SecondscreenGatewayTestSecure", Uri: "ssap://com.webos.service.secondscreen.gateway/test/secure",
+		Response: "bool", Field: "ClearedForDuty", Permission: "TEST_SECURE"},
+	{Name: "Get3DStatus", Uri: "ssap://com.webos.service.tv.display/get3DStatus",
+		Permission: "CONTROL_DISPLAY", Custom: true},
+	{Name: "Set3DOff", Uri: "ssap://com.webos.service.tv.display/set3DOff",
+		Permission: "CONTROL_DISPLAY"},
+	{Name: "Set3DOn", Uri: "ssap://com.webos.service.tv.display/set3DOn",
+		Permission: "CONTROL_DISPLAY"},
+	{Name: "GetCurrentTime", Uri: "ssap://com.webos.service.tv.time/getCurrentTime",
+		Permission: "READ_TV_CURRENT_TIME", Custom: true},
+	// "payload":{"state":"Active","returnValue":true}
+	{Name: "PowerGetState", Uri: "ssap://com.webos.service.tvpower/power/getPowerState",
+		Response: "PowerState", Subscribe: true, Permission: "READ_POWER_STATE"},
+	{Name: "GetCurrentSWInformation", Uri: "ssap://com.webos.service.update/getCurrentSWInformation",
+		Response: "CurrentSWInformation", Permission: "READ_UPDATE_INFO"},
+	{Name: "PairingSetPin", Uri: "ssap://pairing/setPin",
+		Request: "pin string"},
+	// {"type":"response","id":"e8soy4EW","payload":{"features":{"3d":true,"dvr":true},"receiverType":"dvb","modelName":"42LB650V-ZN","returnValue":true}}
+	{Name: "SystemGetSystemInfo", Uri: "ssap://system/getSystemInfo",
+		Response: "SystemInfo"},
+	// {"toastId":"com.webos.service.apiadapter-1522334066285","returnValue":true}
+	{Name: "SystemNotificationsCreateToast", Uri: "ssap://system.notifications/createToast",
+		Request: "message string", Response: "string", Field: "ToastId", Permission: "WRITE_NOTIFICATION_TOAST"},
+	{Name: "SystemTurnOff", Uri: "ssap://system/turnOff",
+		Permission: "CONTROL_POWER"},
+
+	// TODO ssap://com.webos.service.appstatus/getAppStatus // 404 no such service or method
+	// TODO ssap://com.webos.service.bluetooth/gap/findDevices
+	// TODO ssap://com.webos.service.bluetooth/gap/getTrustedDevices
+	// TODO ssap://com.webos.service.bluetooth/gap/isWiFiOnly
+	// TODO ssap://com.webos.service.bluetooth/gap/removeTrustedDevice
+	// TODO ssap://com.webos.service.bluetooth/service/connect
+	// TODO ssap://com.webos.service.bluetooth/service/disconnect
+	// TODO ssap://com.webos.service.bluetooth/service/getStates
+	// TODO ssap://com.webos.service.bluetooth/service/subscribeNotifications
+	// TODO ssap://com.webos.service.connectionmanager/getinfo // 404 no such service or method
+	// TODO ssap://com.webos.service.ime/registerRemoteKeyboard
+	// TODO ssap://com.webos.service.miracast/close
+	// TODO ssap://com.webos.service.miracast/getConnectionStatus
+	// TODO ssap://com.webos.service.miracast/getP2pState
+	// TODO ssap://com.webos.service.miracast/setUACSettings
+	// TODO ssap://com.webos.service.miracast/uibc/getUibcKeyEvent
+	// TODO ssap://com.webos.service.tv.keymanager/listInterestingEvents // error KEYMANAGER_ERROR_0001: Required parameter does not exist - subscribe
+	// TODO ssap://com.webos.service.tvpower/power/turnOnScreen // 404 no such service or method
+	// TODO ssap://com.webos.service.update/getProgress
+	// TODO ssap://com.webos.service.update/getStatus
+	// TODO ssap://com.webos.service.update/startUpdateByRemoteApp
+	// TODO ssap://config/getConfigs // 404 no such service or method
+	// TODO ssap://settings/getSystemSettings // 404 no such service or method
+	// TODO ssap://system/getHostMessage // 404 no such service or method
+	// TODO ssap://system.notifications/createAlert // 404 no such service or method
+	// TODO ssap://timer/getSettings
+	// TODO ssap://timer/setSettings
+	// TODO ssap://tv/getACRAuthToken // 401 insufficient permissions
+	// TODO ssap://user/resetUserInfo
+	// TODO ssap://user/setUserData
+	// TODO ssap://user/setUserInfo // 404 no such service or method
+	// TODO ssap://user/setUserSchedule
+	// TODO ssap://webapp/closeWebApp
+	// TODO ssap://webapp/connectToApp
+	// TODO ssap://webapp/isWebAppPinned
+	// TODO ssap://webapp/launchWebApp
+	// TODO ssap://webapp/pinWebApp
+	// TODO ssap://webapp/removePinnedWebApp
+}
+
+// LookupEndpoint returns the first Endpoint with the given URI.
+func LookupEndpoint(uri string) (ep Endpoint, ok bool) {
+	for _, ep := range Endpoints {
+		if ep.Uri == uri {
+			return ep, true
+		}
+	}
+	return ep, false
+}
@@ -0,0 +1,863 @@
+// Code generated by genendpoints from endpoints.go. DO NOT EDIT.
+
+package webostv
+
+import (
+	"context"
+	"github.com/mitchellh/mapstructure"
+)
+
+// URIs of the Endpoints.
+const (
+	UriApiGetServiceList                      = "ssap://api/getServiceList"
+	UriApplicationManagerGetAppInfo           = "ssap://com.webos.applicationManager/getAppInfo"
+	UriApplicationManagerGetForegroundAppInfo = "ssap://com.webos.applicationManager/getForegroundAppInfo"
+	UriApplicationManagerLaunch               = "ssap://com.webos.applicationManager/launch"
+	UriApplicationManagerListApps             = "ssap://com.webos.applicationManager/listApps"
+	UriApplicationManagerListLaunchPoints     = "ssap://com.webos.applicationManager/listLaunchPoints"
+	UriSystemLauncherClose                    = "ssap://system.launcher/close"
+	UriSystemLauncherGetAppState              = "ssap://system.launcher/getAppState"
+	UriSystemLauncherLaunch                   = "ssap://system.launcher/launch"
+	UriSystemLauncherOpen                     = "ssap://system.launcher/open"
+	UriAudioGetMute                           = "ssap://audio/getMute"
+	UriAudioGetStatus                         = "ssap://audio/getStatus"
+	UriAudioGetVolume                         = "ssap://audio/getVolume"
+	UriAudioSetMute                           = "ssap://audio/setMute"
+	UriAudioSetVolume                         = "ssap://audio/setVolume"
+	UriAudioVolumeDown                        = "ssap://audio/volumeDown"
+	UriAudioVolumeUp                          = "ssap://audio/volumeUp"
+	UriAudioGetSoundOutput                    = "ssap://com.webos.service.apiadapter/audio/getSoundOutput"
+	UriAudioChangeSoundOutput                 = "ssap://com.webos.service.apiadapter/audio/changeSoundOutput"
+	UriMediaControlsFastForward               = "ssap://media.controls/fastForward"
+	UriMediaControlsPause                     = "ssap://media.controls/pause"
+	UriMediaControlsPlay                      = "ssap://media.controls/play"
+	UriMediaControlsRewind                    = "ssap://media.controls/rewind"
+	UriMediaControlsStop                      = "ssap://media.controls/stop"
+	UriMediaViewerClose                       = "ssap://media.viewer/close"
+	UriMediaViewerOpen                        = "ssap://media.viewer/open"
+	UriTvChannelDown                          = "ssap://tv/channelDown"
+	UriTvChannelUp                            = "ssap://tv/channelUp"
+	UriTvGetChannelCurrentProgramInfo         = "ssap://tv/getChannelCurrentProgramInfo"
+	UriTvGetChannelList                       = "ssap://tv/getChannelList"
+	UriTvGetChannelProgramInfo                = "ssap://tv/getChannelProgramInfo"
+	UriTvGetCurrentChannel                    = "ssap://tv/getCurrentChannel"
+	UriTvGetExternalInputList                 = "ssap://tv/getExternalInputList"
+	UriTvOpenChannelId                        = "ssap://tv/openChannel"
+	UriTvOpenChannelNumber                    = "ssap://tv/openChannel"
+	UriTvSwitchInput                          = "ssap://tv/switchInput"
+	UriImeDeleteCharacters                    = "ssap://com.webos.service.ime/deleteCharacters"
+	UriImeInsertText                          = "ssap://com.webos.service.ime/insertText"
+	UriImeSendEnterKey                        = "ssap://com.webos.service.ime/sendEnterKey"
+	UriGetPointerInputSocket                  = "ssap://com.webos.service.networkinput/getPointerInputSocket"
+	UriSdxGetHttpHeaderForServiceRequest      = "ssap://com.webos.service.sdx/getHttpHeaderForServiceRequest"
+	UriSecondscreenGatewayTestSecure          = "ssap://com.webos.service.secondscreen.gateway/test/secure"
+	UriGet3DStatus                            = "ssap://com.webos.service.tv.display/get3DStatus"
+	UriSet3DOff                               = "ssap://com.webos.service.tv.display/set3DOff"
+	UriSet3DOn                                = "ssap://com.webos.service.tv.display/set3DOn"
+	UriGetCurrentTime                         = "ssap://com.webos.service.tv.time/getCurrentTime"
+	UriPowerGetState                          = "ssap://com.webos.service.tvpower/power/getPowerState"
+	UriGetCurrentSWInformation                = "ssap://com.webos.service.update/getCurrentSWInformation"
+	UriPairingSetPin                          = "ssap://pairing/setPin"
+	UriSystemGetSystemInfo                    = "ssap://system/getSystemInfo"
+	UriSystemNotificationsCreateToast         = "ssap://system.notifications/createToast"
+	UriSystemTurnOff                          = "ssap://system/turnOff"
+)
+
+// ApiGetServiceList calls ssap://api/getServiceList.
+func (tv *Tv) ApiGetServiceList() (services []ServiceListEntry, err error) {
+	return tv.ApiGetServiceListContext(context.Background())
+}
+
+// ApiGetServiceListContext is like ApiGetServiceList but honours ctx.
+func (tv *Tv) ApiGetServiceListContext(ctx context.Context) (services []ServiceListEntry, err error) {
+	err = tv.checkSupported(UriApiGetServiceList)
+	if err != nil {
+		return services, err
+	}
+	var resp struct {
+		Services []ServiceListEntry
+	}
+	err = tv.RequestResponseParamContext(ctx, UriApiGetServiceList, nil, &resp)
+	return resp.Services, err
+}
+
+// ApplicationManagerGetAppInfo calls ssap://com.webos.applicationManager/getAppInfo.
+// It requires the READ_INSTALLED_APPS permission.
+func (tv *Tv) ApplicationManagerGetAppInfo(id string) (appInfo App, err error) {
+	return tv.ApplicationManagerGetAppInfoContext(context.Background(), id)
+}
+
+// ApplicationManagerGetAppInfoContext is like ApplicationManagerGetAppInfo but honours ctx.
+func (tv *Tv) ApplicationManagerGetAppInfoContext(ctx context.Context, id string) (appInfo App, err error) {
+	err = tv.checkSupported(UriApplicationManagerGetAppInfo)
+	if err != nil {
+		return appInfo, err
+	}
+	var resp struct {
+		AppInfo App
+	}
+	err = tv.RequestResponseParamContext(ctx, UriApplicationManagerGetAppInfo, Payload{"id": id}, &resp)
+	return resp.AppInfo, err
+}
+
+// ApplicationManagerGetForegroundAppInfo calls ssap://com.webos.applicationManager/getForegroundAppInfo.
+// It requires the READ_RUNNING_APPS permission.
+func (tv *Tv) ApplicationManagerGetForegroundAppInfo() (foregroundAppInfo ForegroundAppInfo, err error) {
+	return tv.ApplicationManagerGetForegroundAppInfoContext(context.Background())
+}
+
+// ApplicationManagerGetForegroundAppInfoContext is like ApplicationManagerGetForegroundAppInfo but honours ctx.
+func (tv *Tv) ApplicationManagerGetForegroundAppInfoContext(ctx context.Context) (foregroundAppInfo ForegroundAppInfo, err error) {
+	err = tv.checkSupported(UriApplicationManagerGetForegroundAppInfo)
+	if err != nil {
+		return foregroundAppInfo, err
+	}
+	err = tv.RequestResponseParamContext(ctx, UriApplicationManagerGetForegroundAppInfo, nil, &foregroundAppInfo)
+	return foregroundAppInfo, err
+}
+
+// ApplicationManagerMonitorForegroundAppInfo subscribes to ssap://com.webos.applicationManager/getForegroundAppInfo
+// and calls process with each result until process returns an error or
+// quit is closed.
+// It requires the READ_RUNNING_APPS permission.
+func (tv *Tv) ApplicationManagerMonitorForegroundAppInfo(process func(foregroundAppInfo ForegroundAppInfo) error, quit <-chan struct{}) error {
+	ctx, cancel := quitContext(quit)
+	defer cancel()
+	return tv.ApplicationManagerMonitorForegroundAppInfoContext(ctx, process)
+}
+
+// ApplicationManagerMonitorForegroundAppInfoContext is like ApplicationManagerMonitorForegroundAppInfo but runs until ctx is done.
+func (tv *Tv) ApplicationManagerMonitorForegroundAppInfoContext(ctx context.Context, process func(foregroundAppInfo ForegroundAppInfo) error) error {
+	err := tv.checkSupported(UriApplicationManagerGetForegroundAppInfo)
+	if err != nil {
+		return err
+	}
+	return tv.MonitorStatusContext(ctx, UriApplicationManagerGetForegroundAppInfo, nil, func(payload Payload) (err error) {
+		var foregroundAppInfo ForegroundAppInfo
+		err = mapstructure.Decode(payload, &foregroundAppInfo)
+		if err == nil {
+			err = process(foregroundAppInfo)
+		}
+		return err
+	})
+}
+
+// ApplicationManagerListApps calls ssap://com.webos.applicationManager/listApps.
+// It requires the READ_INSTALLED_APPS permission.
+func (tv *Tv) ApplicationManagerListApps() (apps []App, err error) {
+	return tv.ApplicationManagerListAppsContext(context.Background())
+}
+
+// ApplicationManagerListAppsContext is like ApplicationManagerListApps but honours ctx.
+func (tv *Tv) ApplicationManagerListAppsContext(ctx context.Context) (apps []App, err error) {
+	err = tv.checkSupported(UriApplicationManagerListApps)
+	if err != nil {
+		return apps, err
+	}
+	var resp struct {
+		Apps []App
+	}
+	err = tv.RequestResponseParamContext(ctx, UriApplicationManagerListApps, nil, &resp)
+	return resp.Apps, err
+}
+
+// SystemLauncherClose calls ssap://system.launcher/close.
+// It requires the CLOSE permission.
+func (tv *Tv) SystemLauncherClose(sessionId string) (err error) {
+	return tv.SystemLauncherCloseContext(context.Background(), sessionId)
+}
+
+// SystemLauncherCloseContext is like SystemLauncherClose but honours ctx.
+func (tv *Tv) SystemLauncherCloseContext(ctx context.Context, sessionId string) (err error) {
+	err = tv.checkSupported(UriSystemLauncherClose)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriSystemLauncherClose, Payload{"sessionId": sessionId})
+	return err
+}
+
+// AudioGetMute calls ssap://audio/getMute.
+// It requires the CONTROL_AUDIO permission.
+func (tv *Tv) AudioGetMute() (mute bool, err error) {
+	return tv.AudioGetMuteContext(context.Background())
+}
+
+// AudioGetMuteContext is like AudioGetMute but honours ctx.
+func (tv *Tv) AudioGetMuteContext(ctx context.Context) (mute bool, err error) {
+	err = tv.checkSupported(UriAudioGetMute)
+	if err != nil {
+		return mute, err
+	}
+	var resp struct {
+		Mute bool
+	}
+	err = tv.RequestResponseParamContext(ctx, UriAudioGetMute, nil, &resp)
+	return resp.Mute, err
+}
+
+// AudioGetStatus calls ssap://audio/getStatus.
+// It requires the CONTROL_AUDIO permission.
+func (tv *Tv) AudioGetStatus() (audioStatus AudioStatus, err error) {
+	return tv.AudioGetStatusContext(context.Background())
+}
+
+// AudioGetStatusContext is like AudioGetStatus but honours ctx.
+func (tv *Tv) AudioGetStatusContext(ctx context.Context) (audioStatus AudioStatus, err error) {
+	err = tv.checkSupported(UriAudioGetStatus)
+	if err != nil {
+		return audioStatus, err
+	}
+	err = tv.RequestResponseParamContext(ctx, UriAudioGetStatus, nil, &audioStatus)
+	return audioStatus, err
+}
+
+// AudioMonitorStatus subscribes to ssap://audio/getStatus
+// and calls process with each result until process returns an error or
+// quit is closed.
+// It requires the CONTROL_AUDIO permission.
+func (tv *Tv) AudioMonitorStatus(process func(audioStatus AudioStatus) error, quit <-chan struct{}) error {
+	ctx, cancel := quitContext(quit)
+	defer cancel()
+	return tv.AudioMonitorStatusContext(ctx, process)
+}
+
+// AudioMonitorStatusContext is like AudioMonitorStatus but runs until ctx is done.
+func (tv *Tv) AudioMonitorStatusContext(ctx context.Context, process func(audioStatus AudioStatus) error) error {
+	err := tv.checkSupported(UriAudioGetStatus)
+	if err != nil {
+		return err
+	}
+	return tv.MonitorStatusContext(ctx, UriAudioGetStatus, nil, func(payload Payload) (err error) {
+		var audioStatus AudioStatus
+		err = mapstructure.Decode(payload, &audioStatus)
+		if err == nil {
+			err = process(audioStatus)
+		}
+		return err
+	})
+}
+
+// AudioSetMute calls ssap://audio/setMute.
+// It requires the CONTROL_AUDIO permission.
+func (tv *Tv) AudioSetMute(mute bool) (err error) {
+	return tv.AudioSetMuteContext(context.Background(), mute)
+}
+
+// AudioSetMuteContext is like AudioSetMute but honours ctx.
+func (tv *Tv) AudioSetMuteContext(ctx context.Context, mute bool) (err error) {
+	err = tv.checkSupported(UriAudioSetMute)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriAudioSetMute, Payload{"mute": mute})
+	return err
+}
+
+// AudioSetVolume calls ssap://audio/setVolume.
+// It requires the CONTROL_AUDIO permission.
+func (tv *Tv) AudioSetVolume(volume int) (err error) {
+	return tv.AudioSetVolumeContext(context.Background(), volume)
+}
+
+// AudioSetVolumeContext is like AudioSetVolume but honours ctx.
+func (tv *Tv) AudioSetVolumeContext(ctx context.Context, volume int) (err error) {
+	err = tv.checkSupported(UriAudioSetVolume)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriAudioSetVolume, Payload{"volume": volume})
+	return err
+}
+
+// AudioVolumeDown calls ssap://audio/volumeDown.
+// It requires the CONTROL_AUDIO permission.
+func (tv *Tv) AudioVolumeDown() (err error) {
+	return tv.AudioVolumeDownContext(context.Background())
+}
+
+// AudioVolumeDownContext is like AudioVolumeDown but honours ctx.
+func (tv *Tv) AudioVolumeDownContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriAudioVolumeDown)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriAudioVolumeDown, nil)
+	return err
+}
+
+// AudioVolumeUp calls ssap://audio/volumeUp.
+// It requires the CONTROL_AUDIO permission.
+func (tv *Tv) AudioVolumeUp() (err error) {
+	return tv.AudioVolumeUpContext(context.Background())
+}
+
+// AudioVolumeUpContext is like AudioVolumeUp but honours ctx.
+func (tv *Tv) AudioVolumeUpContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriAudioVolumeUp)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriAudioVolumeUp, nil)
+	return err
+}
+
+// AudioGetSoundOutput calls ssap://com.webos.service.apiadapter/audio/getSoundOutput.
+// It requires the CONTROL_AUDIO permission.
+func (tv *Tv) AudioGetSoundOutput() (soundOutput string, err error) {
+	return tv.AudioGetSoundOutputContext(context.Background())
+}
+
+// AudioGetSoundOutputContext is like AudioGetSoundOutput but honours ctx.
+func (tv *Tv) AudioGetSoundOutputContext(ctx context.Context) (soundOutput string, err error) {
+	err = tv.checkSupported(UriAudioGetSoundOutput)
+	if err != nil {
+		return soundOutput, err
+	}
+	var resp struct {
+		SoundOutput string
+	}
+	err = tv.RequestResponseParamContext(ctx, UriAudioGetSoundOutput, nil, &resp)
+	return resp.SoundOutput, err
+}
+
+// AudioMonitorSoundOutput subscribes to ssap://com.webos.service.apiadapter/audio/getSoundOutput
+// and calls process with each result until process returns an error or
+// quit is closed.
+// It requires the CONTROL_AUDIO permission.
+func (tv *Tv) AudioMonitorSoundOutput(process func(soundOutput string) error, quit <-chan struct{}) error {
+	ctx, cancel := quitContext(quit)
+	defer cancel()
+	return tv.AudioMonitorSoundOutputContext(ctx, process)
+}
+
+// AudioMonitorSoundOutputContext is like AudioMonitorSoundOutput but runs until ctx is done.
+func (tv *Tv) AudioMonitorSoundOutputContext(ctx context.Context, process func(soundOutput string) error) error {
+	err := tv.checkSupported(UriAudioGetSoundOutput)
+	if err != nil {
+		return err
+	}
+	return tv.MonitorStatusContext(ctx, UriAudioGetSoundOutput, nil, func(payload Payload) (err error) {
+		var resp struct {
+			SoundOutput string
+		}
+		err = mapstructure.Decode(payload, &resp)
+		if err == nil {
+			err = process(resp.SoundOutput)
+		}
+		return err
+	})
+}
+
+// AudioChangeSoundOutput calls ssap://com.webos.service.apiadapter/audio/changeSoundOutput.
+// It requires the CONTROL_AUDIO permission.
+func (tv *Tv) AudioChangeSoundOutput(output string) (err error) {
+	return tv.AudioChangeSoundOutputContext(context.Background(), output)
+}
+
+// AudioChangeSoundOutputContext is like AudioChangeSoundOutput but honours ctx.
+func (tv *Tv) AudioChangeSoundOutputContext(ctx context.Context, output string) (err error) {
+	err = tv.checkSupported(UriAudioChangeSoundOutput)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriAudioChangeSoundOutput, Payload{"output": output})
+	return err
+}
+
+// MediaControlsFastForward calls ssap://media.controls/fastForward.
+// It requires the CONTROL_INPUT_MEDIA_PLAYBACK permission.
+func (tv *Tv) MediaControlsFastForward() (err error) {
+	return tv.MediaControlsFastForwardContext(context.Background())
+}
+
+// MediaControlsFastForwardContext is like MediaControlsFastForward but honours ctx.
+func (tv *Tv) MediaControlsFastForwardContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriMediaControlsFastForward)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriMediaControlsFastForward, nil)
+	return err
+}
+
+// MediaControlsPause calls ssap://media.controls/pause.
+// It requires the CONTROL_INPUT_MEDIA_PLAYBACK permission.
+func (tv *Tv) MediaControlsPause() (err error) {
+	return tv.MediaControlsPauseContext(context.Background())
+}
+
+// MediaControlsPauseContext is like MediaControlsPause but honours ctx.
+func (tv *Tv) MediaControlsPauseContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriMediaControlsPause)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriMediaControlsPause, nil)
+	return err
+}
+
+// MediaControlsPlay calls ssap://media.controls/play.
+// It requires the CONTROL_INPUT_MEDIA_PLAYBACK permission.
+func (tv *Tv) MediaControlsPlay() (err error) {
+	return tv.MediaControlsPlayContext(context.Background())
+}
+
+// MediaControlsPlayContext is like MediaControlsPlay but honours ctx.
+func (tv *Tv) MediaControlsPlayContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriMediaControlsPlay)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriMediaControlsPlay, nil)
+	return err
+}
+
+// MediaControlsRewind calls ssap://media.controls/rewind.
+// It requires the CONTROL_INPUT_MEDIA_PLAYBACK permission.
+func (tv *Tv) MediaControlsRewind() (err error) {
+	return tv.MediaControlsRewindContext(context.Background())
+}
+
+// MediaControlsRewindContext is like MediaControlsRewind but honours ctx.
+func (tv *Tv) MediaControlsRewindContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriMediaControlsRewind)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriMediaControlsRewind, nil)
+	return err
+}
+
+// MediaControlsStop calls ssap://media.controls/stop.
+// It requires the CONTROL_INPUT_MEDIA_PLAYBACK permission.
+func (tv *Tv) MediaControlsStop() (err error) {
+	return tv.MediaControlsStopContext(context.Background())
+}
+
+// MediaControlsStopContext is like MediaControlsStop but honours ctx.
+func (tv *Tv) MediaControlsStopContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriMediaControlsStop)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriMediaControlsStop, nil)
+	return err
+}
+
+// MediaViewerClose calls ssap://media.viewer/close.
+// It requires the CLOSE permission.
+func (tv *Tv) MediaViewerClose(sessionId string) (err error) {
+	return tv.MediaViewerCloseContext(context.Background(), sessionId)
+}
+
+// MediaViewerCloseContext is like MediaViewerClose but honours ctx.
+func (tv *Tv) MediaViewerCloseContext(ctx context.Context, sessionId string) (err error) {
+	err = tv.checkSupported(UriMediaViewerClose)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriMediaViewerClose, Payload{"sessionId": sessionId})
+	return err
+}
+
+// TvChannelDown calls ssap://tv/channelDown.
+// It requires the CONTROL_INPUT_TV permission.
+func (tv *Tv) TvChannelDown() (err error) {
+	return tv.TvChannelDownContext(context.Background())
+}
+
+// TvChannelDownContext is like TvChannelDown but honours ctx.
+func (tv *Tv) TvChannelDownContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriTvChannelDown)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriTvChannelDown, nil)
+	return err
+}
+
+// TvChannelUp calls ssap://tv/channelUp.
+// It requires the CONTROL_INPUT_TV permission.
+func (tv *Tv) TvChannelUp() (err error) {
+	return tv.TvChannelUpContext(context.Background())
+}
+
+// TvChannelUpContext is like TvChannelUp but honours ctx.
+func (tv *Tv) TvChannelUpContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriTvChannelUp)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriTvChannelUp, nil)
+	return err
+}
+
+// TvGetChannelList calls ssap://tv/getChannelList.
+// It requires the READ_TV_CHANNEL_LIST permission.
+func (tv *Tv) TvGetChannelList() (channelList []TvChannel, err error) {
+	return tv.TvGetChannelListContext(context.Background())
+}
+
+// TvGetChannelListContext is like TvGetChannelList but honours ctx.
+func (tv *Tv) TvGetChannelListContext(ctx context.Context) (channelList []TvChannel, err error) {
+	err = tv.checkSupported(UriTvGetChannelList)
+	if err != nil {
+		return channelList, err
+	}
+	var resp struct {
+		ChannelList []TvChannel
+	}
+	err = tv.RequestResponseParamContext(ctx, UriTvGetChannelList, nil, &resp)
+	return resp.ChannelList, err
+}
+
+// TvGetCurrentChannel calls ssap://tv/getCurrentChannel.
+// It requires the READ_CURRENT_CHANNEL permission.
+func (tv *Tv) TvGetCurrentChannel() (tvCurrentChannel TvCurrentChannel, err error) {
+	return tv.TvGetCurrentChannelContext(context.Background())
+}
+
+// TvGetCurrentChannelContext is like TvGetCurrentChannel but honours ctx.
+func (tv *Tv) TvGetCurrentChannelContext(ctx context.Context) (tvCurrentChannel TvCurrentChannel, err error) {
+	err = tv.checkSupported(UriTvGetCurrentChannel)
+	if err != nil {
+		return tvCurrentChannel, err
+	}
+	err = tv.RequestResponseParamContext(ctx, UriTvGetCurrentChannel, nil, &tvCurrentChannel)
+	return tvCurrentChannel, err
+}
+
+// TvMonitorCurrentChannel subscribes to ssap://tv/getCurrentChannel
+// and calls process with each result until process returns an error or
+// quit is closed.
+// It requires the READ_CURRENT_CHANNEL permission.
+func (tv *Tv) TvMonitorCurrentChannel(process func(tvCurrentChannel TvCurrentChannel) error, quit <-chan struct{}) error {
+	ctx, cancel := quitContext(quit)
+	defer cancel()
+	return tv.TvMonitorCurrentChannelContext(ctx, process)
+}
+
+// TvMonitorCurrentChannelContext is like TvMonitorCurrentChannel but runs until ctx is done.
+func (tv *Tv) TvMonitorCurrentChannelContext(ctx context.Context, process func(tvCurrentChannel TvCurrentChannel) error) error {
+	err := tv.checkSupported(UriTvGetCurrentChannel)
+	if err != nil {
+		return err
+	}
+	return tv.MonitorStatusContext(ctx, UriTvGetCurrentChannel, nil, func(payload Payload) (err error) {
+		var tvCurrentChannel TvCurrentChannel
+		err = mapstructure.Decode(payload, &tvCurrentChannel)
+		if err == nil {
+			err = process(tvCurrentChannel)
+		}
+		return err
+	})
+}
+
+// TvGetExternalInputList calls ssap://tv/getExternalInputList.
+// It requires the READ_INPUT_DEVICE_LIST permission.
+func (tv *Tv) TvGetExternalInputList() (devices []TvExternalInput, err error) {
+	return tv.TvGetExternalInputListContext(context.Background())
+}
+
+// TvGetExternalInputListContext is like TvGetExternalInputList but honours ctx.
+func (tv *Tv) TvGetExternalInputListContext(ctx context.Context) (devices []TvExternalInput, err error) {
+	err = tv.checkSupported(UriTvGetExternalInputList)
+	if err != nil {
+		return devices, err
+	}
+	var resp struct {
+		Devices []TvExternalInput
+	}
+	err = tv.RequestResponseParamContext(ctx, UriTvGetExternalInputList, nil, &resp)
+	return resp.Devices, err
+}
+
+// TvOpenChannelId calls ssap://tv/openChannel.
+// It requires the CONTROL_INPUT_TV permission.
+func (tv *Tv) TvOpenChannelId(channelId string) (err error) {
+	return tv.TvOpenChannelIdContext(context.Background(), channelId)
+}
+
+// TvOpenChannelIdContext is like TvOpenChannelId but honours ctx.
+func (tv *Tv) TvOpenChannelIdContext(ctx context.Context, channelId string) (err error) {
+	err = tv.checkSupported(UriTvOpenChannelId)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriTvOpenChannelId, Payload{"channelId": channelId})
+	return err
+}
+
+// TvOpenChannelNumber calls ssap://tv/openChannel.
+// It requires the CONTROL_INPUT_TV permission.
+func (tv *Tv) TvOpenChannelNumber(channelNumber string) (err error) {
+	return tv.TvOpenChannelNumberContext(context.Background(), channelNumber)
+}
+
+// TvOpenChannelNumberContext is like TvOpenChannelNumber but honours ctx.
+func (tv *Tv) TvOpenChannelNumberContext(ctx context.Context, channelNumber string) (err error) {
+	err = tv.checkSupported(UriTvOpenChannelNumber)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriTvOpenChannelNumber, Payload{"channelNumber": channelNumber})
+	return err
+}
+
+// TvSwitchInput calls ssap://tv/switchInput.
+// It requires the CONTROL_INPUT_TV permission.
+func (tv *Tv) TvSwitchInput(inputId string) (err error) {
+	return tv.TvSwitchInputContext(context.Background(), inputId)
+}
+
+// TvSwitchInputContext is like TvSwitchInput but honours ctx.
+func (tv *Tv) TvSwitchInputContext(ctx context.Context, inputId string) (err error) {
+	err = tv.checkSupported(UriTvSwitchInput)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriTvSwitchInput, Payload{"inputId": inputId})
+	return err
+}
+
+// ImeDeleteCharacters calls ssap://com.webos.service.ime/deleteCharacters.
+// It requires the CONTROL_INPUT_TEXT permission.
+func (tv *Tv) ImeDeleteCharacters(count int) (err error) {
+	return tv.ImeDeleteCharactersContext(context.Background(), count)
+}
+
+// ImeDeleteCharactersContext is like ImeDeleteCharacters but honours ctx.
+func (tv *Tv) ImeDeleteCharactersContext(ctx context.Context, count int) (err error) {
+	err = tv.checkSupported(UriImeDeleteCharacters)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriImeDeleteCharacters, Payload{"count": count})
+	return err
+}
+
+// ImeInsertText calls ssap://com.webos.service.ime/insertText.
+// It requires the CONTROL_INPUT_TEXT permission.
+func (tv *Tv) ImeInsertText(text string, replace bool) (err error) {
+	return tv.ImeInsertTextContext(context.Background(), text, replace)
+}
+
+// ImeInsertTextContext is like ImeInsertText but honours ctx.
+func (tv *Tv) ImeInsertTextContext(ctx context.Context, text string, replace bool) (err error) {
+	err = tv.checkSupported(UriImeInsertText)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriImeInsertText, Payload{"text": text, "replace": replace})
+	return err
+}
+
+// ImeSendEnterKey calls ssap://com.webos.service.ime/sendEnterKey.
+// It requires the CONTROL_INPUT_TEXT permission.
+func (tv *Tv) ImeSendEnterKey() (err error) {
+	return tv.ImeSendEnterKeyContext(context.Background())
+}
+
+// ImeSendEnterKeyContext is like ImeSendEnterKey but honours ctx.
+func (tv *Tv) ImeSendEnterKeyContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriImeSendEnterKey)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriImeSendEnterKey, nil)
+	return err
+}
+
+// GetPointerInputSocket calls ssap://com.webos.service.networkinput/getPointerInputSocket.
+// It requires the CONTROL_MOUSE_AND_KEYBOARD permission.
+func (tv *Tv) GetPointerInputSocket() (socketPath string, err error) {
+	return tv.GetPointerInputSocketContext(context.Background())
+}
+
+// GetPointerInputSocketContext is like GetPointerInputSocket but honours ctx.
+func (tv *Tv) GetPointerInputSocketContext(ctx context.Context) (socketPath string, err error) {
+	err = tv.checkSupported(UriGetPointerInputSocket)
+	if err != nil {
+		return socketPath, err
+	}
+	var resp struct {
+		SocketPath string
+	}
+	err = tv.RequestResponseParamContext(ctx, UriGetPointerInputSocket, nil, &resp)
+	return resp.SocketPath, err
+}
+
+// SecondscreenGatewayTestSecure calls ssap://com.webos.service.secondscreen.gateway/test/secure.
+// It requires the TEST_SECURE permission.
+func (tv *Tv) SecondscreenGatewayTestSecure() (clearedForDuty bool, err error) {
+	return tv.SecondscreenGatewayTestSecureContext(context.Background())
+}
+
+// SecondscreenGatewayTestSecureContext is like SecondscreenGatewayTestSecure but honours ctx.
+func (tv *Tv) SecondscreenGatewayTestSecureContext(ctx context.Context) (clearedForDuty bool, err error) {
+	err = tv.checkSupported(UriSecondscreenGatewayTestSecure)
+	if err != nil {
+		return clearedForDuty, err
+	}
+	var resp struct {
+		ClearedForDuty bool
+	}
+	err = tv.RequestResponseParamContext(ctx, UriSecondscreenGatewayTestSecure, nil, &resp)
+	return resp.ClearedForDuty, err
+}
+
+// Set3DOff calls ssap://com.webos.service.tv.display/set3DOff.
+// It requires the CONTROL_DISPLAY permission.
+func (tv *Tv) Set3DOff() (err error) {
+	return tv.Set3DOffContext(context.Background())
+}
+
+// Set3DOffContext is like Set3DOff but honours ctx.
+func (tv *Tv) Set3DOffContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriSet3DOff)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriSet3DOff, nil)
+	return err
+}
+
+// Set3DOn calls ssap://com.webos.service.tv.display/set3DOn.
+// It requires the CONTROL_DISPLAY permission.
+func (tv *Tv) Set3DOn() (err error) {
+	return tv.Set3DOnContext(context.Background())
+}
+
+// Set3DOnContext is like Set3DOn but honours ctx.
+func (tv *Tv) Set3DOnContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriSet3DOn)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriSet3DOn, nil)
+	return err
+}
+
+// PowerGetState calls ssap://com.webos.service.tvpower/power/getPowerState.
+// It requires the READ_POWER_STATE permission.
+func (tv *Tv) PowerGetState() (powerState PowerState, err error) {
+	return tv.PowerGetStateContext(context.Background())
+}
+
+// PowerGetStateContext is like PowerGetState but honours ctx.
+func (tv *Tv) PowerGetStateContext(ctx context.Context) (powerState PowerState, err error) {
+	err = tv.checkSupported(UriPowerGetState)
+	if err != nil {
+		return powerState, err
+	}
+	err = tv.RequestResponseParamContext(ctx, UriPowerGetState, nil, &powerState)
+	return powerState, err
+}
+
+// PowerMonitorState subscribes to ssap://com.webos.service.tvpower/power/getPowerState
+// and calls process with each result until process returns an error or
+// quit is closed.
+// It requires the READ_POWER_STATE permission.
+func (tv *Tv) PowerMonitorState(process func(powerState PowerState) error, quit <-chan struct{}) error {
+	ctx, cancel := quitContext(quit)
+	defer cancel()
+	return tv.PowerMonitorStateContext(ctx, process)
+}
+
+// PowerMonitorStateContext is like PowerMonitorState but runs until ctx is done.
+func (tv *Tv) PowerMonitorStateContext(ctx context.Context, process func(powerState PowerState) error) error {
+	err := tv.checkSupported(UriPowerGetState)
+	if err != nil {
+		return err
+	}
+	return tv.MonitorStatusContext(ctx, UriPowerGetState, nil, func(payload Payload) (err error) {
+		var powerState PowerState
+		err = mapstructure.Decode(payload, &powerState)
+		if err == nil {
+			err = process(powerState)
+		}
+		return err
+	})
+}
+
+// GetCurrentSWInformation calls ssap://com.webos.service.update/getCurrentSWInformation.
+// It requires the READ_UPDATE_INFO permission.
+func (tv *Tv) GetCurrentSWInformation() (currentSWInformation CurrentSWInformation, err error) {
+	return tv.GetCurrentSWInformationContext(context.Background())
+}
+
+// GetCurrentSWInformationContext is like GetCurrentSWInformation but honours ctx.
+func (tv *Tv) GetCurrentSWInformationContext(ctx context.Context) (currentSWInformation CurrentSWInformation, err error) {
+	err = tv.checkSupported(UriGetCurrentSWInformation)
+	if err != nil {
+		return currentSWInformation, err
+	}
+	err = tv.RequestResponseParamContext(ctx, UriGetCurrentSWInformation, nil, &currentSWInformation)
+	return currentSWInformation, err
+}
+
+// PairingSetPin calls ssap://pairing/setPin.
+func (tv *Tv) PairingSetPin(pin string) (err error) {
+	return tv.PairingSetPinContext(context.Background(), pin)
+}
+
+// PairingSetPinContext is like PairingSetPin but honours ctx.
+func (tv *Tv) PairingSetPinContext(ctx context.Context, pin string) (err error) {
+	err = tv.checkSupported(UriPairingSetPin)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriPairingSetPin, Payload{"pin": pin})
+	return err
+}
+
+// SystemGetSystemInfo calls ssap://system/getSystemInfo.
+func (tv *Tv) SystemGetSystemInfo() (systemInfo SystemInfo, err error) {
+	return tv.SystemGetSystemInfoContext(context.Background())
+}
+
+// SystemGetSystemInfoContext is like SystemGetSystemInfo but honours ctx.
+func (tv *Tv) SystemGetSystemInfoContext(ctx context.Context) (systemInfo SystemInfo, err error) {
+	err = tv.checkSupported(UriSystemGetSystemInfo)
+	if err != nil {
+		return systemInfo, err
+	}
+	err = tv.RequestResponseParamContext(ctx, UriSystemGetSystemInfo, nil, &systemInfo)
+	return systemInfo, err
+}
+
+// SystemNotificationsCreateToast calls ssap://system.notifications/createToast.
+// It requires the WRITE_NOTIFICATION_TOAST permission.
+func (tv *Tv) SystemNotificationsCreateToast(message string) (toastId string, err error) {
+	return tv.SystemNotificationsCreateToastContext(context.Background(), message)
+}
+
+// SystemNotificationsCreateToastContext is like SystemNotificationsCreateToast but honours ctx.
+func (tv *Tv) SystemNotificationsCreateToastContext(ctx context.Context, message string) (toastId string, err error) {
+	err = tv.checkSupported(UriSystemNotificationsCreateToast)
+	if err != nil {
+		return toastId, err
+	}
+	var resp struct {
+		ToastId string
+	}
+	err = tv.RequestResponseParamContext(ctx, UriSystemNotificationsCreateToast, Payload{"message": message}, &resp)
+	return resp.ToastId, err
+}
+
+// SystemTurnOff calls ssap://system/turnOff.
+// It requires the CONTROL_POWER permission.
+func (tv *Tv) SystemTurnOff() (err error) {
+	return tv.SystemTurnOffContext(context.Background())
+}
+
+// SystemTurnOffContext is like SystemTurnOff but honours ctx.
+func (tv *Tv) SystemTurnOffContext(ctx context.Context) (err error) {
+	err = tv.checkSupported(UriSystemTurnOff)
+	if err != nil {
+		return err
+	}
+	_, err = tv.RequestContext(ctx, UriSystemTurnOff, nil)
+	return err
+}
@@ -0,0 +1,60 @@
+package webostv_test
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"testing"
+	"time"
+)
+
+func TestGeneratedEndpoints(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse(webostv.UriPowerGetState, webostv.Payload{"state": "Active"})
+	output := make(chan string, 1)
+	s.Handle(webostv.UriAudioChangeSoundOutput, func(req webostv.Payload) (webostv.Payload, error) {
+		output <- req["output"].(string)
+		return nil, nil
+	})
+	s.HandleResponse(webostv.UriAudioGetSoundOutput, webostv.Payload{"soundOutput": "tv_speaker"})
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	ps, err := tv.PowerGetState()
+	if err != nil || ps.State != "Active" {
+		t.Errorf("unexpected power state %+v, %v", ps, err)
+	}
+	err = tv.AudioChangeSoundOutput("external_arc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := <-output; o != "external_arc" {
+		t.Errorf("unexpected sound output in request: %q", o)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var outputs []string
+	err = tv.AudioMonitorSoundOutputContext(ctx, func(o string) error {
+		outputs = append(outputs, o)
+		if len(outputs) == 1 {
+			s.Publish(webostv.UriAudioGetSoundOutput, webostv.Payload{"soundOutput": "external_arc"})
+			return nil
+		}
+		return errors.New("done")
+	})
+	if err == nil || err.Error() != "done" {
+		t.Fatal(err)
+	}
+	if len(outputs) != 2 || outputs[0] != "tv_speaker" || outputs[1] != "external_arc" {
+		t.Errorf("unexpected monitored sound outputs %v", outputs)
+	}
+
+	ep, ok := webostv.LookupEndpoint("ssap://audio/getStatus")
+	if !ok || ep.Name != "AudioGetStatus" || !ep.Subscribe || ep.Permission != "CONTROL_AUDIO" {
+		t.Errorf("unexpected endpoint %+v", ep)
+	}
+}
@@ -0,0 +1,122 @@
+package webostv
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"strings"
+	"sync"
+)
+
+// EventQueueSize is the capacity of the channel returned by Tv.Events.
+var EventQueueSize = 32
+
+// EventKind tells what kind of an unsolicited message an Event carries.
+type EventKind string
+
+const (
+	EventHello   EventKind = "hello"   // "hello" message with device information
+	EventPairing EventKind = "pairing" // pairing prompt or PIN request
+	EventToast   EventKind = "toast"   // toast or alert related notification
+	EventError   EventKind = "error"   // error not related to any request
+	EventOther   EventKind = "other"   // anything else
+)
+
+// Event is a message from the TV which does not belong to any pending
+// request or active subscription, or a pairing prompt in response to
+// Register.
+type Event struct {
+	Kind  EventKind
+	Msg   Msg
+	Hello *HelloInfo // set if Kind is EventHello
+}
+
+type HelloInfo struct {
+	ProtocolVersion        int      // "protocolVersion":1
+	DeviceType             string   // "deviceType":"tv"
+	DeviceOS               string   // "deviceOS":"webOS"
+	DeviceOSVersion        string   // "deviceOSVersion":"4.1.0"
+	DeviceOSReleaseVersion string   // "deviceOSReleaseVersion":"3.0"
+	DeviceUUID             string   // "deviceUUID":"ee5b3b1f-..."
+	PairingTypes           []string // "pairingTypes":["PIN","PROMPT","COMBINED"]
+}
+
+type eventHandlers struct {
+	sync.Mutex
+	funcs  []func(Event)
+	ch     chan Event
+	closed bool
+}
+
+func decodeEvent(msg Msg) (ev Event) {
+	ev.Msg = msg
+	switch {
+	case msg.Type == "hello":
+		ev.Kind = EventHello
+		var info HelloInfo
+		if mapstructure.Decode(msg.Payload, &info) == nil {
+			ev.Hello = &info
+		}
+	case msg.Type == "error":
+		ev.Kind = EventError
+	case msg.Payload["pairingType"] != nil:
+		ev.Kind = EventPairing
+	case msg.Payload["toastId"] != nil || msg.Payload["alertId"] != nil ||
+		strings.HasPrefix(msg.Uri, "ssap://system.notifications/"):
+		ev.Kind = EventToast
+	default:
+		ev.Kind = EventOther
+	}
+	return ev
+}
+
+// OnEvent adds a function which is called for every unsolicited message
+// from the TV and for the pairing prompts seen by Register. The functions
+// are called from the message handler goroutine or from Register, so they
+// must not block.
+func (tv *Tv) OnEvent(f func(Event)) {
+	tv.events.Lock()
+	tv.events.funcs = append(tv.events.funcs, f)
+	tv.events.Unlock()
+}
+
+// Events returns a channel which receives the unsolicited messages from
+// the TV. If the channel is full, new events are dropped. The channel is
+// closed when the message handler exits.
+func (tv *Tv) Events() <-chan Event {
+	tv.events.Lock()
+	defer tv.events.Unlock()
+	if tv.events.ch == nil {
+		tv.events.ch = make(chan Event, EventQueueSize)
+		if tv.events.closed {
+			close(tv.events.ch)
+		}
+	}
+	return tv.events.ch
+}
+
+func (tv *Tv) emitEvent(msg Msg) {
+	ev := decodeEvent(msg)
+
+	tv.events.Lock()
+	funcs := tv.events.funcs
+	if tv.events.ch != nil && !tv.events.closed {
+		select {
+		case tv.events.ch <- ev:
+		default:
+			tv.logWarn("event channel full, event dropped")
+		}
+	}
+	tv.events.Unlock()
+
+	for _, f := range funcs {
+		f(ev)
+	}
+}
+
+func (tv *Tv) closeEvents() {
+	tv.events.Lock()
+	if tv.events.ch != nil && !tv.events.closed {
+		close(tv.events.ch)
+	}
+	tv.events.closed = true
+	tv.events.Unlock()
+}
@@ -0,0 +1,78 @@
+package webostv_test
+
+import (
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tv.Close()
+	events := tv.Events()
+	go tv.MessageHandler()
+
+	s.Send(webostv.Msg{
+		Type: "hello",
+		Payload: webostv.Payload{
+			"deviceOS":     "webOS",
+			"deviceUUID":   "ee5b3b1f-a8a0-4b52-9a2b-3fe4a6e4ce1a",
+			"pairingTypes": []string{"PIN", "PROMPT"},
+		},
+	})
+	s.Send(webostv.Msg{
+		Type:    "response",
+		Id:      "unknown",
+		Payload: webostv.Payload{"toastId": "com.webos.service.apiadapter-1"},
+	})
+
+	select {
+	case ev := <-events:
+		if ev.Kind != webostv.EventHello || ev.Hello == nil {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+		if ev.Hello.DeviceUUID != "ee5b3b1f-a8a0-4b52-9a2b-3fe4a6e4ce1a" || len(ev.Hello.PairingTypes) != 2 {
+			t.Errorf("unexpected hello info: %+v", ev.Hello)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("hello event not received")
+	}
+	select {
+	case ev := <-events:
+		if ev.Kind != webostv.EventToast {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("toast event not received")
+	}
+}
+
+func TestPairingEvent(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	tv := dialTestTv(t, s)
+	defer tv.Close()
+	var kinds []webostv.EventKind
+	var pairingType interface{}
+	tv.OnEvent(func(ev webostv.Event) {
+		kinds = append(kinds, ev.Kind)
+		if ev.Kind == webostv.EventPairing {
+			pairingType = ev.Msg.Payload["pairingType"]
+		}
+	})
+	_, err := tv.Register("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kinds) != 1 || kinds[0] != webostv.EventPairing || pairingType != webostv.PairingTypePrompt {
+		t.Errorf("unexpected events %v, pairing type %v", kinds, pairingType)
+	}
+}
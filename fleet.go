@@ -0,0 +1,376 @@
+package webostv
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotConnected is the error of a FleetResult for a TV which is not
+// connected.
+var ErrNotConnected = errors.New("not connected")
+
+// Fleet manages the connections to many TVs, keyed by name. The TVs are
+// connected with Connect, after which operations can be run on all of
+// them concurrently with Do and the broadcast methods, and their
+// subscriptions can be aggregated with Subscribe. The connections are
+// supervised: lost connections are re-established in the background, see
+// Tv.SupervisedMessageHandler.
+//
+// The client keys of the TVs are kept in the KeyStore of the Dialer of the
+// Fleet. If it has none, the keys are kept in memory, so the TVs must be
+// paired again after restarting the program.
+type Fleet struct {
+	// RegisterOptions are used for registering each TV. ClientKey is
+	// ignored, the keys are taken from the KeyStore.
+	RegisterOptions RegisterOptions
+	// MaxConcurrency limits the number of TVs which are operated on at
+	// the same time. Zero means no limit.
+	MaxConcurrency int
+
+	dialer *Dialer
+
+	mutex   sync.Mutex
+	members map[string]*fleetMember
+}
+
+type fleetMember struct {
+	address string
+	tv      *Tv                // nil if not connected
+	cancel  context.CancelFunc // stops the message handler of tv
+	err     error              // why the TV is not connected, if it is not
+}
+
+// FleetResult is the outcome of an operation on one TV of a Fleet.
+type FleetResult struct {
+	Name  string
+	Value interface{} // result of the operation, if any
+	Err   error
+}
+
+// FleetResults are the outcomes of an operation on the TVs of a Fleet,
+// sorted by name.
+type FleetResults []FleetResult
+
+// Err returns a FleetError if the operation failed on any TV, nil
+// otherwise.
+func (rs FleetResults) Err() error {
+	var failed FleetResults
+	for _, r := range rs {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	if failed == nil {
+		return nil
+	}
+	return &FleetError{Failed: failed}
+}
+
+// FleetError is returned by FleetResults.Err.
+type FleetError struct {
+	Failed FleetResults // the results of the TVs on which the operation failed
+}
+
+func (e *FleetError) Error() string {
+	msgs := make([]string, len(e.Failed))
+	for i, r := range e.Failed {
+		msgs[i] = r.Name + ": " + r.Err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// FleetMsg is a subscription message from a TV of a Fleet.
+type FleetMsg struct {
+	Name string // name of the TV
+	Msg
+}
+
+// NewFleet returns an empty Fleet which connects to the TVs with dialer,
+// or DefaultDialer if dialer is nil.
+func NewFleet(dialer *Dialer) *Fleet {
+	if dialer == nil {
+		dialer = &DefaultDialer
+	}
+	d := *dialer
+	if d.KeyStore == nil {
+		d.KeyStore = NewMemoryKeyStore()
+	}
+	return &Fleet{
+		dialer:  &d,
+		members: make(map[string]*fleetMember),
+	}
+}
+
+// Add adds the TV at address to the Fleet with the given name. It is
+// connected by the next call of Connect.
+func (f *Fleet) Add(name, address string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if _, ok := f.members[name]; ok {
+		return errors.Errorf("TV %q is already in the fleet", name)
+	}
+	f.members[name] = &fleetMember{
+		address: address,
+		err:     ErrNotConnected,
+	}
+	return nil
+}
+
+// Remove disconnects the TV with the given name and removes it from the
+// Fleet.
+func (f *Fleet) Remove(name string) {
+	f.mutex.Lock()
+	m := f.members[name]
+	delete(f.members, name)
+	f.mutex.Unlock()
+	if m != nil && m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// Names returns the names of the TVs in the Fleet in sorted order.
+func (f *Fleet) Names() (names []string) {
+	f.mutex.Lock()
+	for name := range f.members {
+		names = append(names, name)
+	}
+	f.mutex.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// Tv returns the connected Tv with the given name, or nil.
+func (f *Fleet) Tv(name string) *Tv {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if m := f.members[name]; m != nil {
+		return m.tv
+	}
+	return nil
+}
+
+// Connect connects to and registers with the TVs which are not connected,
+// concurrently. The results tell which TVs are connected.
+func (f *Fleet) Connect(ctx context.Context) FleetResults {
+	return f.run(ctx, f.Names(), func(ctx context.Context, name string) (interface{}, error) {
+		f.mutex.Lock()
+		m := f.members[name]
+		f.mutex.Unlock()
+		if m == nil {
+			return nil, ErrNotConnected
+		}
+		if f.Tv(name) != nil {
+			return nil, nil
+		}
+		return nil, f.connect(ctx, name, m)
+	})
+}
+
+func (f *Fleet) connect(ctx context.Context, name string, m *fleetMember) error {
+	tv, err := f.dialer.DialContext(ctx, m.address)
+	if err != nil {
+		f.setErr(m, nil, err)
+		return err
+	}
+	handlerCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		err := tv.SupervisedMessageHandler(handlerCtx)
+		if err == nil {
+			err = ErrNotConnected
+		}
+		f.setErr(m, tv, err)
+		close(done)
+	}()
+
+	opts := f.RegisterOptions
+	opts.ClientKey = ""
+	_, err = tv.RegisterWithOptions(ctx, opts)
+	if err != nil {
+		cancel()
+		<-done
+		f.setErr(m, nil, err)
+		return err
+	}
+
+	f.mutex.Lock()
+	if f.members[name] == m {
+		select {
+		case <-done:
+		default:
+			m.tv = tv
+			m.cancel = cancel
+			m.err = nil
+			f.mutex.Unlock()
+			return nil
+		}
+	}
+	f.mutex.Unlock()
+	// removed or disconnected while connecting
+	cancel()
+	return ErrNotConnected
+}
+
+// setErr marks m as not connected because of err if its Tv is tv.
+func (f *Fleet) setErr(m *fleetMember, tv *Tv, err error) {
+	f.mutex.Lock()
+	if m.tv == tv {
+		m.tv = nil
+		m.cancel = nil
+		m.err = err
+	}
+	f.mutex.Unlock()
+}
+
+// Close disconnects all TVs. The Fleet can be connected again.
+func (f *Fleet) Close() {
+	f.mutex.Lock()
+	var cancels []context.CancelFunc
+	for _, m := range f.members {
+		if m.cancel != nil {
+			cancels = append(cancels, m.cancel)
+		}
+	}
+	f.mutex.Unlock()
+	for _, cancel := range cancels {
+		cancel()
+	}
+}
+
+// run calls op concurrently for each of names and collects the results.
+func (f *Fleet) run(ctx context.Context, names []string, op func(ctx context.Context, name string) (interface{}, error)) FleetResults {
+	results := make(FleetResults, len(names))
+	var sem chan struct{}
+	if f.MaxConcurrency > 0 {
+		sem = make(chan struct{}, f.MaxConcurrency)
+	}
+	var wg sync.WaitGroup
+	for i, name := range names {
+		results[i].Name = name
+		wg.Add(1)
+		go func(r *FleetResult) {
+			defer wg.Done()
+			if sem != nil {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-ctx.Done():
+					r.Err = contextError(ctx)
+					return
+				}
+			}
+			r.Value, r.Err = op(ctx, r.Name)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+// Do calls op concurrently with each TV of the Fleet and returns the
+// results. The result of a TV which is not connected is the reason it is
+// not connected, such as ErrNotConnected.
+func (f *Fleet) Do(ctx context.Context, op func(ctx context.Context, tv *Tv) (interface{}, error)) FleetResults {
+	return f.run(ctx, f.Names(), func(ctx context.Context, name string) (interface{}, error) {
+		f.mutex.Lock()
+		m := f.members[name]
+		var tv *Tv
+		err := ErrNotConnected
+		if m != nil {
+			tv, err = m.tv, m.err
+		}
+		f.mutex.Unlock()
+		if tv == nil {
+			return nil, err
+		}
+		return op(ctx, tv)
+	})
+}
+
+// CreateToast shows a toast message on all TVs. The values of the
+// results are the toast ids.
+func (f *Fleet) CreateToast(ctx context.Context, message string) FleetResults {
+	return f.Do(ctx, func(ctx context.Context, tv *Tv) (interface{}, error) {
+		return tv.SystemNotificationsCreateToastContext(ctx, message)
+	})
+}
+
+// TurnOff turns off all TVs.
+func (f *Fleet) TurnOff(ctx context.Context) FleetResults {
+	return f.Do(ctx, func(ctx context.Context, tv *Tv) (interface{}, error) {
+		return nil, tv.SystemTurnOffContext(ctx)
+	})
+}
+
+// SwitchInput switches all TVs to the input inputId, such as "HDMI_1".
+func (f *Fleet) SwitchInput(ctx context.Context, inputId string) FleetResults {
+	return f.Do(ctx, func(ctx context.Context, tv *Tv) (interface{}, error) {
+		return nil, tv.TvSwitchInputContext(ctx, inputId)
+	})
+}
+
+// Subscribe subscribes to uri on all connected TVs and sends the messages
+// to msgCh with the name of the TV until ctx is done, after which msgCh is
+// closed. The results tell on which TVs subscribing succeeded. TVs which
+// are connected later are not subscribed to.
+func (f *Fleet) Subscribe(ctx context.Context, uri string, req Payload, msgCh chan<- FleetMsg) FleetResults {
+	var wg sync.WaitGroup
+	results := f.Do(ctx, func(ctx context.Context, tv *Tv) (interface{}, error) {
+		err := tv.checkSupported(uri)
+		if err != nil {
+			return nil, err
+		}
+		ch := make(chan Msg, 1)
+		id, err := tv.SubscribeContext(ctx, uri, req, ch)
+		if err != nil {
+			return nil, err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.forward(ctx, tv, uri, id, ch, msgCh)
+		}()
+		return nil, nil
+	})
+	go func() {
+		<-ctx.Done()
+		wg.Wait()
+		close(msgCh)
+	}()
+	return results
+}
+
+// forward sends the messages of the subscription id of tv to msgCh until
+// ctx is done or the subscription ends.
+func (f *Fleet) forward(ctx context.Context, tv *Tv, uri, id string, ch <-chan Msg, msgCh chan<- FleetMsg) {
+	name := f.nameOf(tv)
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			select {
+			case msgCh <- FleetMsg{Name: name, Msg: msg}:
+			case <-ctx.Done():
+			}
+		case <-ctx.Done():
+			tv.Unsubscribe(uri, id, nil)
+			return
+		}
+	}
+}
+
+// nameOf returns the name of tv in the Fleet.
+func (f *Fleet) nameOf(tv *Tv) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for name, m := range f.members {
+		if m.tv == tv {
+			return name
+		}
+	}
+	return tv.Address
+}
@@ -0,0 +1,101 @@
+package webostv_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"net"
+	"testing"
+)
+
+func TestFleet(t *testing.T) {
+	servers := make(map[string]*webostvtest.Server)
+	for i, name := range []string{"tv1", "tv2"} {
+		name := name
+		s := webostvtest.NewServer()
+		defer s.Close()
+		s.UUID = fmt.Sprintf("00000000-0000-0000-0000-00000000000%d", i)
+		s.Handle(webostv.UriSystemNotificationsCreateToast, func(req webostv.Payload) (webostv.Payload, error) {
+			return webostv.Payload{"toastId": name + "-" + req["message"].(string)}, nil
+		})
+		s.HandleResponse("ssap://audio/getStatus", webostv.Payload{"volume": 1})
+		servers[name] = s
+	}
+	servers["tv1"].HandleResponse(webostv.UriSystemTurnOff, nil)
+
+	// route the dialed host names to the servers
+	dialer := servers["tv1"].Dialer()
+	wsDialer := *dialer.WebsocketDialer
+	wsDialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, _ := net.SplitHostPort(addr)
+		s := servers[host]
+		if s == nil {
+			return nil, errors.Errorf("no such host %s", host)
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, network, s.Address)
+	}
+	dialer.WebsocketDialer = &wsDialer
+
+	f := webostv.NewFleet(dialer)
+	defer f.Close()
+	for _, name := range []string{"a", "b", "c"} {
+		err := f.Add(name, map[string]string{"a": "tv1", "b": "tv2", "c": "tv3"}[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Add("a", "tv2"); err == nil {
+		t.Error("adding a duplicate name succeeded")
+	}
+
+	results := f.Connect(context.Background())
+	if len(results) != 3 || results[0].Err != nil || results[1].Err != nil || results[2].Err == nil {
+		t.Fatalf("unexpected connect results: %+v", results)
+	}
+	if f.Tv("a") == nil || f.Tv("c") != nil {
+		t.Error("unexpected connection state")
+	}
+
+	results = f.CreateToast(context.Background(), "hi")
+	if results[0].Value != "tv1-hi" || results[1].Value != "tv2-hi" || results[2].Err == nil {
+		t.Errorf("unexpected toast results: %+v", results)
+	}
+
+	results = f.TurnOff(context.Background())
+	err, ok := results.Err().(*webostv.FleetError)
+	if !ok || len(err.Failed) != 2 || err.Failed[0].Name != "b" || !webostv.IsNotFound(err.Failed[0].Err) ||
+		err.Failed[1].Name != "c" {
+		t.Errorf("unexpected turn off error: %v", results.Err())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	msgCh := make(chan webostv.FleetMsg, 10)
+	results = f.Subscribe(ctx, "ssap://audio/getStatus", nil, msgCh)
+	if results[0].Err != nil || results[1].Err != nil {
+		t.Fatalf("unexpected subscribe results: %+v", results)
+	}
+	initial := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		msg := <-msgCh
+		initial[msg.Name] = true
+	}
+	if !initial["a"] || !initial["b"] {
+		t.Errorf("unexpected initial messages from %v", initial)
+	}
+	servers["tv2"].Publish("ssap://audio/getStatus", webostv.Payload{"volume": 2})
+	msg := <-msgCh
+	if msg.Name != "b" || msg.Payload["volume"] != float64(2) {
+		t.Errorf("unexpected pushed message: %+v", msg)
+	}
+	cancel()
+	for range msgCh {
+	}
+
+	f.Remove("a")
+	if names := f.Names(); len(names) != 2 || names[0] != "b" {
+		t.Errorf("unexpected names after Remove: %v", names)
+	}
+}
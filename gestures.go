@@ -0,0 +1,183 @@
+package webostv
+
+import (
+	"context"
+	"image"
+	"time"
+)
+
+var (
+	// GlideStep is the maximum distance in pixels moved by a single move
+	// message in Glide, MoveTo and Drag. If it is zero or negative, the
+	// pointer is moved in a single step.
+	GlideStep = 10
+	// GlideInterval is the minimum time between the move messages sent
+	// by Glide, MoveTo and Drag. Zero or negative means no limit.
+	GlideInterval = time.Millisecond * 10
+	// KeyRepeatInterval is the interval of the repeated button presses
+	// sent by LongPress.
+	KeyRepeatInterval = time.Millisecond * 100
+)
+
+// PointerDown presses the pointer button. Moves made while the button is
+// held down drag the item under the pointer.
+func (ps *PointerSocket) PointerDown() (err error) {
+	return ps.PointerDownContext(context.Background())
+}
+
+// PointerDownContext is like PointerDown but honours ctx.
+func (ps *PointerSocket) PointerDownContext(ctx context.Context) (err error) {
+	return ps.setPointerButton(ctx, true)
+}
+
+// PointerUp releases the pointer button.
+func (ps *PointerSocket) PointerUp() (err error) {
+	return ps.PointerUpContext(context.Background())
+}
+
+// PointerUpContext is like PointerUp but honours ctx.
+func (ps *PointerSocket) PointerUpContext(ctx context.Context) (err error) {
+	return ps.setPointerButton(ctx, false)
+}
+
+func (ps *PointerSocket) setPointerButton(ctx context.Context, down bool) (err error) {
+	err = ps.writePointerButton(ctx, "move", 0, 0, down)
+	if err == nil {
+		ps.Lock()
+		ps.down = down
+		ps.Unlock()
+	}
+	return err
+}
+
+// Position returns the pointer position relative to its position when the
+// PointerSocket was opened. The TV does not report the real position of
+// the pointer, so this is the sum of the moves made.
+func (ps *PointerSocket) Position() image.Point {
+	ps.Lock()
+	defer ps.Unlock()
+	return image.Point{X: ps.x, Y: ps.y}
+}
+
+// Glide moves the pointer by (dx, dy) in small steps spread over the
+// duration d so that the cursor moves smoothly on the screen.
+func (ps *PointerSocket) Glide(ctx context.Context, dx, dy int, d time.Duration) (err error) {
+	if dx == 0 && dy == 0 {
+		return nil
+	}
+	step, minInterval := GlideStep, GlideInterval
+	if step <= 0 {
+		return ps.MoveContext(ctx, dx, dy)
+	}
+	steps := abs(dx)
+	if abs(dy) > steps {
+		steps = abs(dy)
+	}
+	steps = (steps + step - 1) / step
+	if minInterval > 0 {
+		if maxSteps := int(d / minInterval); steps > maxSteps {
+			steps = maxSteps
+		}
+	}
+	if steps < 1 {
+		return ps.MoveContext(ctx, dx, dy)
+	}
+	interval := d / time.Duration(steps)
+
+	var movedX, movedY int
+	for i := 1; i <= steps; i++ {
+		// interpolate so that rounding errors do not accumulate
+		x := dx * i / steps
+		y := dy * i / steps
+		err = ps.MoveContext(ctx, x-movedX, y-movedY)
+		if err != nil {
+			return err
+		}
+		movedX, movedY = x, y
+		if i == steps {
+			break
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// MoveTo glides the pointer to the given position over the duration d.
+// See Position for the coordinate system.
+func (ps *PointerSocket) MoveTo(ctx context.Context, pos image.Point, d time.Duration) (err error) {
+	cur := ps.Position()
+	return ps.Glide(ctx, pos.X-cur.X, pos.Y-cur.Y, d)
+}
+
+// Drag moves the pointer to the first point of path, presses the pointer
+// button, glides through the rest of the points and releases the button.
+// Each segment of the path takes the duration d. See Position for the
+// coordinate system.
+func (ps *PointerSocket) Drag(ctx context.Context, path []image.Point, d time.Duration) (err error) {
+	if len(path) == 0 {
+		return nil
+	}
+	err = ps.MoveTo(ctx, path[0], d)
+	if err != nil {
+		return err
+	}
+	err = ps.PointerDownContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, pos := range path[1:] {
+		err = ps.MoveTo(ctx, pos, d)
+		if err != nil {
+			ps.PointerUp()
+			return err
+		}
+	}
+	return ps.PointerUp()
+}
+
+// LongPress simulates holding down a remote control button for the
+// duration d by repeating the button press like the remote control does.
+func (ps *PointerSocket) LongPress(ctx context.Context, b Button, d time.Duration) (err error) {
+	deadline := time.Now().Add(d)
+	for {
+		err = ps.PressContext(ctx, b)
+		if err != nil {
+			return err
+		}
+		if !time.Now().Add(KeyRepeatInterval).Before(deadline) {
+			return nil
+		}
+		select {
+		case <-time.After(KeyRepeatInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Hold presses the pointer button, keeps it down for the duration d and
+// releases it.
+func (ps *PointerSocket) Hold(ctx context.Context, d time.Duration) (err error) {
+	err = ps.PointerDownContext(ctx)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+		ps.PointerUp()
+		return ctx.Err()
+	}
+	return ps.PointerUp()
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
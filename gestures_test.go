@@ -0,0 +1,99 @@
+package webostv_test
+
+import (
+	"context"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"image"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPointerGestures(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	ps, err := tv.NewPointerSocket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+
+	err = ps.Drag(context.Background(), []image.Point{{0, 0}, {35, -20}}, time.Millisecond*40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos := ps.Position(); pos != (image.Point{35, -20}) {
+		t.Errorf("unexpected position %v", pos)
+	}
+
+	ev := <-s.PointerEvents()
+	if ev["type"] != "move" || ev["down"] != "1" {
+		t.Fatalf("expected pointer down, got %v", ev)
+	}
+	var dx, dy int
+	for {
+		ev = <-s.PointerEvents()
+		if ev["down"] == "0" {
+			break
+		}
+		x, _ := strconv.Atoi(ev["dx"])
+		y, _ := strconv.Atoi(ev["dy"])
+		if x > webostv.GlideStep || -y > webostv.GlideStep {
+			t.Errorf("too large step: %v", ev)
+		}
+		dx += x
+		dy += y
+	}
+	if dx != 35 || dy != -20 {
+		t.Errorf("dragged by (%d, %d), expected (35, -20)", dx, dy)
+	}
+}
+
+func TestGlideWithoutLimits(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	ps, err := tv.NewPointerSocket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+
+	defer func(step int, interval time.Duration) {
+		webostv.GlideStep, webostv.GlideInterval = step, interval
+	}(webostv.GlideStep, webostv.GlideInterval)
+
+	moves := func(n int) (steps []string) {
+		for i := 0; i < n; i++ {
+			ev := <-s.PointerEvents()
+			steps = append(steps, ev["dx"]+","+ev["dy"])
+		}
+		return steps
+	}
+
+	webostv.GlideStep, webostv.GlideInterval = 0, 0
+	err = ps.Glide(context.Background(), 30, -10, time.Millisecond*100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps := moves(1); steps[0] != "30,-10" {
+		t.Errorf("unexpected steps %v without step limit", steps)
+	}
+
+	webostv.GlideStep = 10
+	err = ps.Glide(context.Background(), 25, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps := strings.Join(moves(3), " "); steps != "8,0 8,0 9,0" {
+		t.Errorf("unexpected steps %v without interval limit", steps)
+	}
+}
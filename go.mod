@@ -3,7 +3,10 @@ module github.com/snabb/webostv
 go 1.12
 
 require (
+	github.com/eclipse/paho.mqtt.golang v1.2.0
 	github.com/gorilla/websocket v1.4.0
 	github.com/mitchellh/mapstructure v1.1.2
+	github.com/ogier/pflag v0.0.1
 	github.com/pkg/errors v0.8.1
+	golang.org/x/net v0.11.0 // indirect
 )
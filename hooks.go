@@ -0,0 +1,55 @@
+package webostv
+
+import (
+	"time"
+)
+
+// Hooks are functions which Tv calls to report its activity, for example
+// to collect metrics. All of them are optional. They are called
+// synchronously from the goroutine doing the work, so they must be fast
+// and must not block.
+type Hooks struct {
+	// Request is called when a request completes with its URI, the time
+	// it took and the error, if any.
+	Request func(uri string, d time.Duration, err error)
+	// SubscriptionMessage is called for each message which arrives to
+	// the subscription of uri. dropped tells if a queued message had to
+	// be dropped because the subscriber is too slow. Messages replaced
+	// with OverflowCoalesce are not counted as dropped.
+	SubscriptionMessage func(uri string, dropped bool)
+	// Disconnect is called when the connection to the TV is lost.
+	Disconnect func(err error)
+	// Reconnect is called when SupervisedMessageHandler has connected to
+	// the TV again. err tells if resuming the session failed.
+	Reconnect func(err error)
+}
+
+// SetHooks sets the Hooks of tv. It should be called before the message
+// handler is started.
+func (tv *Tv) SetHooks(hooks Hooks) {
+	tv.hooks = hooks
+}
+
+func (tv *Tv) hookRequest(uri string, start time.Time, err error) {
+	if tv.hooks.Request != nil {
+		tv.hooks.Request(uri, time.Since(start), err)
+	}
+}
+
+func (tv *Tv) hookSubscriptionMessage(uri string, dropped bool) {
+	if tv.hooks.SubscriptionMessage != nil {
+		tv.hooks.SubscriptionMessage(uri, dropped)
+	}
+}
+
+func (tv *Tv) hookDisconnect(err error) {
+	if tv.hooks.Disconnect != nil {
+		tv.hooks.Disconnect(err)
+	}
+}
+
+func (tv *Tv) hookReconnect(err error) {
+	if tv.hooks.Reconnect != nil {
+		tv.hooks.Reconnect(err)
+	}
+}
@@ -0,0 +1,83 @@
+package webostv_test
+
+import (
+	"context"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse("ssap://audio/getStatus", webostv.Payload{"volume": 1})
+
+	defer func(d time.Duration) { webostv.ReconnectMinDelay = d }(webostv.ReconnectMinDelay)
+	webostv.ReconnectMinDelay = time.Millisecond * 10
+
+	var mu sync.Mutex
+	var requests, errs, subMsgs, disconnects int
+	reconnected := make(chan error, 1)
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tv.SetHooks(webostv.Hooks{
+		Request: func(uri string, d time.Duration, err error) {
+			mu.Lock()
+			requests++
+			if err != nil {
+				errs++
+			}
+			mu.Unlock()
+		},
+		SubscriptionMessage: func(uri string, dropped bool) {
+			mu.Lock()
+			subMsgs++
+			mu.Unlock()
+		},
+		Disconnect: func(err error) {
+			mu.Lock()
+			disconnects++
+			mu.Unlock()
+		},
+		Reconnect: func(err error) {
+			reconnected <- err
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tv.SupervisedMessageHandler(ctx)
+	_, err = tv.Register("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tv.AudioGetStatus()
+	tv.Request("ssap://no/such/uri", nil)
+	msgCh := make(chan webostv.Msg, 1)
+	_, err = tv.Subscribe("ssap://audio/getStatus", nil, msgCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-msgCh
+
+	s.DropConnections()
+	select {
+	case err = <-reconnected:
+		if err != nil {
+			t.Errorf("reconnect failed: %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Reconnect hook was not called")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 2 || errs != 1 || subMsgs < 1 || disconnects != 1 {
+		t.Errorf("unexpected hook calls: %d requests, %d errors, %d subscription messages, %d disconnects",
+			requests, errs, subMsgs, disconnects)
+	}
+}
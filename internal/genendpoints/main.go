@@ -0,0 +1,357 @@
+// Command genendpoints generates the ssap wrapper methods of the webostv
+// package from the Endpoints registry. It is run by "go generate" in the
+// directory of the package.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// endpoint mirrors webostv.Endpoint. It is parsed from the source instead
+// of imported, so that the package does not need to compile for the
+// generator to run.
+type endpoint struct {
+	Name       string
+	Uri        string
+	Request    string
+	Response   string
+	Field      string
+	Subscribe  bool
+	Permission string
+	Custom     bool
+
+	Params []param // parsed from Request
+}
+
+type param struct {
+	Name string
+	Type string
+}
+
+func main() {
+	in := flag.String("in", "endpoints.go", "file containing the Endpoints registry")
+	out := flag.String("out", "endpoints_gen.go", "output file")
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("genendpoints: ")
+
+	eps, err := parseEndpoints(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := generate(*in, eps)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = ioutil.WriteFile(*out, src, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseEndpoints reads the composite literal assigned to Endpoints in the
+// file fn.
+func parseEndpoints(fn string) (eps []endpoint, err error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, fn, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var list *ast.CompositeLit
+	ast.Inspect(f, func(n ast.Node) bool {
+		vs, ok := n.(*ast.ValueSpec)
+		if !ok || len(vs.Names) != 1 || vs.Names[0].Name != "Endpoints" || len(vs.Values) != 1 {
+			return true
+		}
+		list, _ = vs.Values[0].(*ast.CompositeLit)
+		return false
+	})
+	if list == nil {
+		return nil, fmt.Errorf("%s: Endpoints not found", fn)
+	}
+
+	names := make(map[string]bool)
+	for _, elt := range list.Elts {
+		lit, ok := elt.(*ast.CompositeLit)
+		if !ok {
+			return nil, fmt.Errorf("%s: Endpoint is not a composite literal", fset.Position(elt.Pos()))
+		}
+		var ep endpoint
+		for _, e := range lit.Elts {
+			kv, ok := e.(*ast.KeyValueExpr)
+			if !ok {
+				return nil, fmt.Errorf("%s: Endpoint fields must be keyed", fset.Position(e.Pos()))
+			}
+			err = setField(&ep, kv)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", fset.Position(kv.Pos()), err)
+			}
+		}
+		err = ep.check(names)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fset.Position(lit.Pos()), err)
+		}
+		names[ep.Name] = true
+		eps = append(eps, ep)
+	}
+	return eps, nil
+}
+
+func setField(ep *endpoint, kv *ast.KeyValueExpr) error {
+	key, ok := kv.Key.(*ast.Ident)
+	if !ok {
+		return fmt.Errorf("invalid key")
+	}
+	switch v := kv.Value.(type) {
+	case *ast.BasicLit:
+		s, err := strconv.Unquote(v.Value)
+		if err != nil || v.Kind != token.STRING {
+			return fmt.Errorf("invalid value of %s", key.Name)
+		}
+		switch key.Name {
+		case "Name":
+			ep.Name = s
+		case "Uri":
+			ep.Uri = s
+		case "Request":
+			ep.Request = s
+		case "Response":
+			ep.Response = s
+		case "Field":
+			ep.Field = s
+		case "Permission":
+			ep.Permission = s
+		default:
+			return fmt.Errorf("unknown string field %s", key.Name)
+		}
+	case *ast.Ident:
+		if v.Name != "true" && v.Name != "false" {
+			return fmt.Errorf("invalid value of %s", key.Name)
+		}
+		b := v.Name == "true"
+		switch key.Name {
+		case "Subscribe":
+			ep.Subscribe = b
+		case "Custom":
+			ep.Custom = b
+		default:
+			return fmt.Errorf("unknown bool field %s", key.Name)
+		}
+	default:
+		return fmt.Errorf("value of %s must be a literal", key.Name)
+	}
+	return nil
+}
+
+// reserved are the identifiers used by the generated code.
+var reserved = map[string]bool{
+	"tv": true, "ctx": true, "err": true, "resp": true,
+	"payload": true, "process": true, "quit": true, "cancel": true,
+}
+
+func (ep *endpoint) check(names map[string]bool) error {
+	if ep.Name == "" || ep.Uri == "" {
+		return fmt.Errorf("Name and Uri are required")
+	}
+	if names[ep.Name] {
+		return fmt.Errorf("duplicate Name %s", ep.Name)
+	}
+	if ep.Field != "" && ep.Response == "" {
+		return fmt.Errorf("%s: Field requires Response", ep.Name)
+	}
+	if ep.Request != "" {
+		for _, decl := range strings.Split(ep.Request, ",") {
+			fields := strings.Fields(decl)
+			if len(fields) < 2 {
+				return fmt.Errorf("%s: invalid Request %q", ep.Name, ep.Request)
+			}
+			p := param{
+				Name: fields[0],
+				Type: strings.Join(fields[1:], " "),
+			}
+			if reserved[p.Name] || p.Name == ep.ResultName() {
+				return fmt.Errorf("%s: parameter name %s is reserved", ep.Name, p.Name)
+			}
+			ep.Params = append(ep.Params, p)
+		}
+	}
+	if ep.Subscribe && !ep.Custom && !strings.Contains(ep.Name, "Get") {
+		return fmt.Errorf("%s: the Name of a subscribable endpoint must contain \"Get\"", ep.Name)
+	}
+	return nil
+}
+
+// ResultName returns the name of the result variable.
+func (ep *endpoint) ResultName() string {
+	name := ep.Field
+	if name == "" {
+		name = ep.Response
+	}
+	name = strings.TrimLeft(name, "[]*")
+	if name == "" {
+		return ""
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// MonitorName returns the name of the Monitor method.
+func (ep *endpoint) MonitorName() string {
+	return strings.Replace(ep.Name, "Get", "Monitor", 1)
+}
+
+// ParamDecls returns the parameter declarations, preceded by a comma.
+func (ep *endpoint) ParamDecls() string {
+	var b strings.Builder
+	for _, p := range ep.Params {
+		b.WriteString(", " + p.Name + " " + p.Type)
+	}
+	return b.String()
+}
+
+// ParamNames returns the parameter names, preceded by a comma.
+func (ep *endpoint) ParamNames() string {
+	var b strings.Builder
+	for _, p := range ep.Params {
+		b.WriteString(", " + p.Name)
+	}
+	return b.String()
+}
+
+// Payload returns the expression of the request payload.
+func (ep *endpoint) Payload() string {
+	if len(ep.Params) == 0 {
+		return "nil"
+	}
+	var kvs []string
+	for _, p := range ep.Params {
+		kvs = append(kvs, strconv.Quote(p.Name)+": "+p.Name)
+	}
+	return "Payload{" + strings.Join(kvs, ", ") + "}"
+}
+
+func (ep *endpoint) Results() string {
+	if ep.Response == "" {
+		return "err error"
+	}
+	return ep.ResultName() + " " + ep.Response + ", err error"
+}
+
+func (ep *endpoint) Requires() string {
+	if ep.Permission == "" {
+		return ""
+	}
+	return "\n// It requires the " + ep.Permission + " permission."
+}
+
+var funcs = template.FuncMap{
+	"trimComma": func(s string) string {
+		return strings.TrimPrefix(s, ", ")
+	},
+}
+
+var tmpl = template.Must(template.New("").Funcs(funcs).Parse(`// Code generated by genendpoints from {{.In}}. DO NOT EDIT.
+
+package webostv
+
+import (
+	"context"
+	"github.com/mitchellh/mapstructure"
+)
+
+// URIs of the Endpoints.
+const (
+{{- range .Endpoints}}
+	Uri{{.Name}} = {{printf "%q" .Uri}}
+{{- end}}
+)
+{{range .Endpoints}}{{if not .Custom}}
+// {{.Name}} calls {{.Uri}}.{{.Requires}}
+func (tv *Tv) {{.Name}}({{trimComma .ParamDecls}}) ({{.Results}}) {
+	return tv.{{.Name}}Context(context.Background(){{.ParamNames}})
+}
+
+// {{.Name}}Context is like {{.Name}} but honours ctx.
+func (tv *Tv) {{.Name}}Context(ctx context.Context{{.ParamDecls}}) ({{.Results}}) {
+	err = tv.checkSupported(Uri{{.Name}})
+	if err != nil {
+		return {{if .Response}}{{.ResultName}}, {{end}}err
+	}
+{{- if not .Response}}
+	_, err = tv.RequestContext(ctx, Uri{{.Name}}, {{.Payload}})
+	return err
+{{- else if .Field}}
+	var resp struct {
+		{{.Field}} {{.Response}}
+	}
+	err = tv.RequestResponseParamContext(ctx, Uri{{.Name}}, {{.Payload}}, &resp)
+	return resp.{{.Field}}, err
+{{- else}}
+	err = tv.RequestResponseParamContext(ctx, Uri{{.Name}}, {{.Payload}}, &{{.ResultName}})
+	return {{.ResultName}}, err
+{{- end}}
+}
+{{if .Subscribe}}
+// {{.MonitorName}} subscribes to {{.Uri}}
+// and calls process with each result until process returns an error or
+// quit is closed.{{.Requires}}
+func (tv *Tv) {{.MonitorName}}({{trimComma .ParamDecls}}{{if .Params}}, {{end}}process func({{.ResultName}} {{.Response}}) error, quit <-chan struct{}) error {
+	ctx, cancel := quitContext(quit)
+	defer cancel()
+	return tv.{{.MonitorName}}Context(ctx{{.ParamNames}}, process)
+}
+
+// {{.MonitorName}}Context is like {{.MonitorName}} but runs until ctx is done.
+func (tv *Tv) {{.MonitorName}}Context(ctx context.Context{{.ParamDecls}}, process func({{.ResultName}} {{.Response}}) error) error {
+	err := tv.checkSupported(Uri{{.Name}})
+	if err != nil {
+		return err
+	}
+	return tv.MonitorStatusContext(ctx, Uri{{.Name}}, {{.Payload}}, func(payload Payload) (err error) {
+{{- if .Field}}
+		var resp struct {
+			{{.Field}} {{.Response}}
+		}
+		err = mapstructure.Decode(payload, &resp)
+		if err == nil {
+			err = process(resp.{{.Field}})
+		}
+{{- else}}
+		var {{.ResultName}} {{.Response}}
+		err = mapstructure.Decode(payload, &{{.ResultName}})
+		if err == nil {
+			err = process({{.ResultName}})
+		}
+{{- end}}
+		return err
+	})
+}
+{{end}}{{end}}{{end}}`))
+
+func generate(in string, eps []endpoint) ([]byte, error) {
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, struct {
+		In        string
+		Endpoints []endpoint
+	}{in, eps})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		os.Stderr.Write(buf.Bytes())
+		return nil, err
+	}
+	return src, nil
+}
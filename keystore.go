@@ -0,0 +1,253 @@
+package webostv
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Credentials are the pairing credentials of a TV kept in a KeyStore.
+type Credentials struct {
+	ClientKey   string `json:"clientKey,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"` // see Tv.Fingerprint
+	Address     string `json:"address,omitempty"`     // address of the last registration
+	MAC         string `json:"mac,omitempty"`         // for Wake-on-LAN, see Dialer.PowerOn
+}
+
+// KeyStore stores the Credentials of TVs keyed by the UUID of the TV. If
+// Dialer.KeyStore is set, Register loads the client key from it and saves
+// the new one after a successful registration.
+type KeyStore interface {
+	// Load returns the Credentials of the TV. Zero Credentials and a nil
+	// error are returned if there are none.
+	Load(uuid string) (cred Credentials, err error)
+	// Save stores the Credentials of the TV.
+	Save(uuid string, cred Credentials) (err error)
+}
+
+// AddressLookup is implemented by KeyStores which can find the
+// Credentials of a TV by the address it was last registered at. The UUID
+// of a TV which is turned off can not be asked, so Dialer.PowerOn finds
+// the MAC address this way.
+type AddressLookup interface {
+	LookupAddress(address string) (uuid string, cred Credentials, err error)
+}
+
+// lookupAddress returns the first of creds registered at address.
+func lookupAddress(creds map[string]Credentials, address string) (uuid string, cred Credentials) {
+	for uuid, cred := range creds {
+		if address != "" && cred.Address == address {
+			return uuid, cred
+		}
+	}
+	return "", Credentials{}
+}
+
+// MemoryKeyStore is a KeyStore which keeps the Credentials in memory.
+type MemoryKeyStore struct {
+	sync.Mutex
+	creds map[string]Credentials
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		creds: make(map[string]Credentials),
+	}
+}
+
+func (ks *MemoryKeyStore) Load(uuid string) (cred Credentials, err error) {
+	ks.Lock()
+	defer ks.Unlock()
+	return ks.creds[uuid], nil
+}
+
+func (ks *MemoryKeyStore) Save(uuid string, cred Credentials) (err error) {
+	ks.Lock()
+	defer ks.Unlock()
+	ks.creds[uuid] = cred
+	return nil
+}
+
+func (ks *MemoryKeyStore) LookupAddress(address string) (uuid string, cred Credentials, err error) {
+	ks.Lock()
+	defer ks.Unlock()
+	uuid, cred = lookupAddress(ks.creds, address)
+	return uuid, cred, nil
+}
+
+// FileKeyStoreLockTimeout is the maximum time FileKeyStore waits for the
+// lock of the file. A lock older than this is considered stale and is
+// removed.
+var FileKeyStoreLockTimeout = time.Second * 10
+
+// FileKeyStore is a KeyStore which keeps the Credentials in a JSON file
+// readable only by its owner. The file is replaced atomically on every
+// change, and a lock file serializes the changes between processes.
+type FileKeyStore struct {
+	Path string
+}
+
+// NewFileKeyStore returns a FileKeyStore using the file at path. If path
+// is empty, DefaultKeyStorePath is used.
+func NewFileKeyStore(path string) (ks *FileKeyStore, err error) {
+	if path == "" {
+		path, err = DefaultKeyStorePath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &FileKeyStore{Path: path}, nil
+}
+
+// DefaultKeyStorePath returns "webostv/keys.json" in the user specific
+// configuration directory, for example ~/.config/webostv/keys.json.
+func DefaultKeyStorePath() (path string, err error) {
+	dir, err := userConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "webostv", "keys.json"), nil
+}
+
+// userConfigDir returns the configuration directory following the XDG
+// Base Directory Specification and the platform conventions.
+func userConfigDir() (dir string, err error) {
+	if dir = os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir, nil
+	}
+	if runtime.GOOS == "windows" {
+		if dir = os.Getenv("AppData"); dir != "" {
+			return dir, nil
+		}
+		return "", errors.New("%AppData% is not defined")
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return "", errors.New("$HOME is not defined")
+	}
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, "Library", "Application Support"), nil
+	}
+	return filepath.Join(home, ".config"), nil
+}
+
+func (ks *FileKeyStore) Load(uuid string) (cred Credentials, err error) {
+	creds, err := ks.readAll()
+	if err != nil {
+		return cred, err
+	}
+	return creds[uuid], nil
+}
+
+func (ks *FileKeyStore) LookupAddress(address string) (uuid string, cred Credentials, err error) {
+	creds, err := ks.readAll()
+	if err != nil {
+		return "", cred, err
+	}
+	uuid, cred = lookupAddress(creds, address)
+	return uuid, cred, nil
+}
+
+func (ks *FileKeyStore) Save(uuid string, cred Credentials) (err error) {
+	err = os.MkdirAll(filepath.Dir(ks.Path), 0700)
+	if err != nil {
+		return errors.Wrap(err, "key store")
+	}
+	unlock, err := ks.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	creds, err := ks.readAll()
+	if err != nil {
+		return err
+	}
+	if creds[uuid] == cred {
+		return nil
+	}
+	creds[uuid] = cred
+	return ks.writeAll(creds)
+}
+
+func (ks *FileKeyStore) readAll() (creds map[string]Credentials, err error) {
+	creds = make(map[string]Credentials)
+	buf, err := ioutil.ReadFile(ks.Path)
+	if os.IsNotExist(err) {
+		return creds, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "key store")
+	}
+	if len(buf) == 0 {
+		return creds, nil
+	}
+	err = json.Unmarshal(buf, &creds)
+	if err != nil {
+		return nil, errors.Wrap(err, "key store "+ks.Path)
+	}
+	return creds, nil
+}
+
+// writeAll writes creds to a temporary file and renames it over the
+// key store file so that readers never see a partially written file.
+func (ks *FileKeyStore) writeAll(creds map[string]Credentials) (err error) {
+	buf, err := json.MarshalIndent(creds, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "JSON marshal error")
+	}
+	f, err := ioutil.TempFile(filepath.Dir(ks.Path), filepath.Base(ks.Path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "key store")
+	}
+	tmpName := f.Name()
+	_, err = f.Write(append(buf, '\n'))
+	if err == nil {
+		err = f.Chmod(0600)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(tmpName, ks.Path)
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return errors.Wrap(err, "key store")
+	}
+	return nil
+}
+
+// lock creates the lock file of the key store, waiting if another process
+// holds it. The returned function removes the lock file.
+func (ks *FileKeyStore) lock() (unlock func(), err error) {
+	name := ks.Path + ".lock"
+	deadline := time.Now().Add(FileKeyStoreLockTimeout)
+	for {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, errors.Wrap(err, "key store lock")
+		}
+		if fi, err := os.Stat(name); err == nil && time.Since(fi.ModTime()) > FileKeyStoreLockTimeout {
+			os.Remove(name)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("key store lock " + name + " is held by another process")
+		}
+		time.Sleep(time.Millisecond * 50)
+	}
+	// not reached
+}
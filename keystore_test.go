@@ -0,0 +1,116 @@
+package webostv_test
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestKeyStore(t *testing.T) {
+	s := webostvtest.NewTLSServer()
+	defer s.Close()
+
+	prompted := 0
+	s.PairingFunc = func() bool {
+		prompted++
+		return true
+	}
+	s.HandleResponse(webostv.UriGetCurrentSWInformation, webostv.Payload{"device_id": "3c:cd:93:7b:91:9e"})
+
+	dialer := s.Dialer()
+	dialer.KeyStore = webostv.NewMemoryKeyStore()
+	for i := 0; i < 2; i++ {
+		tv := dialTestTvWith(t, dialer, s)
+		result, err := tv.RegisterWithOptions(context.Background(), webostv.RegisterOptions{})
+		tv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.UUID != s.UUID {
+			t.Errorf("got UUID %q, expected %q", result.UUID, s.UUID)
+		}
+	}
+	if prompted != 1 {
+		t.Errorf("pairing prompted %d times, expected once", prompted)
+	}
+	cred, err := dialer.KeyStore.Load(s.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.ClientKey == "" || cred.Fingerprint != webostv.CertificateFingerprint(s.Certificate()) ||
+		cred.Address != s.Address || cred.MAC != "3c:cd:93:7b:91:9e" {
+		t.Errorf("unexpected credentials: %+v", cred)
+	}
+	mac, err := dialer.KnownMAC(s.Address)
+	if err != nil || mac != cred.MAC {
+		t.Errorf("KnownMAC = %q, %v", mac, err)
+	}
+	err = s.Dialer().PowerOn(context.Background(), s.Address, "", "", "")
+	if errors.Cause(err) != webostv.ErrUnknownMAC {
+		t.Errorf("expected ErrUnknownMAC, got %v", err)
+	}
+
+	cred.Fingerprint = strings.Repeat("00", 32)
+	dialer.KeyStore.Save(s.UUID, cred)
+	tv := dialTestTvWith(t, dialer, s)
+	defer tv.Close()
+	_, err = tv.Register("")
+	if !webostv.IsCertificateMismatch(err) {
+		t.Errorf("expected CertificateMismatchError, got %v", err)
+	}
+}
+
+func TestFileKeyStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webostv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks, err := webostv.NewFileKeyStore(filepath.Join(dir, "sub", "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := ks.Load("tv1")
+	if err != nil || cred != (webostv.Credentials{}) {
+		t.Fatalf("Load from missing file: %+v, %v", cred, err)
+	}
+	for i, uuid := range []string{"tv1", "tv2", "tv1"} {
+		err = ks.Save(uuid, webostv.Credentials{ClientKey: strconv.Itoa(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for uuid, key := range map[string]string{"tv1": "2", "tv2": "1"} {
+		cred, err = ks.Load(uuid)
+		if err != nil || cred.ClientKey != key {
+			t.Errorf("Load(%q) = %+v, %v; expected client key %q", uuid, cred, err, key)
+		}
+	}
+	err = ks.Save("tv2", webostv.Credentials{ClientKey: "1", Address: "192.0.2.2", MAC: "3c:cd:93:7b:91:9e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uuid, cred, err := ks.LookupAddress("192.0.2.2")
+	if err != nil || uuid != "tv2" || cred.MAC != "3c:cd:93:7b:91:9e" {
+		t.Errorf("LookupAddress = %q, %+v, %v", uuid, cred, err)
+	}
+	fi, err := os.Stat(ks.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm() != 0600 {
+		t.Errorf("key store has permissions %v", fi.Mode().Perm())
+	}
+	if _, err = os.Stat(ks.Path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("lock file was not removed: %v", err)
+	}
+}
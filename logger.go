@@ -0,0 +1,105 @@
+package webostv
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Logger receives structured log records from Tv. The methods take a
+// message and alternating keys and values in the style of log/slog;
+// *slog.Logger satisfies the interface.
+//
+// Each message exchanged with the TV is logged at debug level as "ssap
+// message" with the keys "dir" (FrameSent or FrameReceived), "type", "id",
+// "uri", "payload" and, for error messages, "error". The client key is
+// redacted from the payload. Each completed request is logged at debug
+// level as "ssap request" with the keys "id", "uri", "latency" and, if it
+// failed, "error". Lost connections and dropped messages are logged at
+// warning level.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+}
+
+// SetLogger sets the Logger of tv. It should be called before the message
+// handler is started.
+func (tv *Tv) SetLogger(l Logger) {
+	tv.logger = l
+}
+
+// logDebug logs at debug level to the Logger and the debug function.
+func (tv *Tv) logDebug(msg string, keyvals ...interface{}) {
+	if tv.logger != nil {
+		tv.logger.Debug(msg, keyvals...)
+	}
+	tv.debugKeyvals(msg, keyvals)
+}
+
+// logWarn logs at warning level to the Logger and the debug function.
+func (tv *Tv) logWarn(msg string, keyvals ...interface{}) {
+	if tv.logger != nil {
+		tv.logger.Warn(msg, keyvals...)
+	}
+	tv.debugKeyvals(msg, keyvals)
+}
+
+// debugKeyvals passes msg to the debug function with the values appended.
+func (tv *Tv) debugKeyvals(msg string, keyvals []interface{}) {
+	if tv.debugFunc == nil {
+		return
+	}
+	for i := 1; i < len(keyvals); i += 2 {
+		msg += ": " + fmt.Sprint(keyvals[i])
+	}
+	tv.debugFunc(msg)
+}
+
+// logMessage logs a message exchanged with the TV.
+func (tv *Tv) logMessage(dir string, msg *Msg) {
+	if tv.logger == nil {
+		return
+	}
+	payload, _ := json.Marshal(redactClientKey(msg.Payload))
+	keyvals := []interface{}{
+		"dir", dir,
+		"type", msg.Type,
+		"id", msg.Id,
+		"uri", msg.Uri,
+		"payload", json.RawMessage(payload),
+	}
+	if msg.Error != "" {
+		keyvals = append(keyvals, "error", msg.Error)
+	}
+	tv.logger.Debug("ssap message", keyvals...)
+}
+
+// logRequest logs a completed request.
+func (tv *Tv) logRequest(id, uri string, start time.Time, err error) {
+	if tv.logger == nil {
+		return
+	}
+	keyvals := []interface{}{
+		"id", id,
+		"uri", uri,
+		"latency", time.Since(start),
+	}
+	if err != nil {
+		keyvals = append(keyvals, "error", err)
+	}
+	tv.logger.Debug("ssap request", keyvals...)
+}
+
+// redactClientKey returns p with the client key replaced by "REDACTED".
+// p is not modified.
+func redactClientKey(p Payload) Payload {
+	if _, ok := p["client-key"]; !ok {
+		return p
+	}
+	redacted := make(Payload, len(p))
+	for k, v := range p {
+		redacted[k] = v
+	}
+	redacted["client-key"] = "REDACTED"
+	return redacted
+}
@@ -0,0 +1,92 @@
+package webostv_test
+
+import (
+	"fmt"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type logRecord struct {
+	level   string
+	msg     string
+	keyvals map[string]interface{}
+}
+
+type testLogger struct {
+	sync.Mutex
+	records []logRecord
+}
+
+func (l *testLogger) log(level, msg string, keyvals []interface{}) {
+	r := logRecord{level: level, msg: msg, keyvals: make(map[string]interface{})}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		r.keyvals[keyvals[i].(string)] = keyvals[i+1]
+	}
+	l.Lock()
+	l.records = append(l.records, r)
+	l.Unlock()
+}
+
+func (l *testLogger) Debug(msg string, keyvals ...interface{}) { l.log("debug", msg, keyvals) }
+func (l *testLogger) Warn(msg string, keyvals ...interface{})  { l.log("warn", msg, keyvals) }
+
+func TestLogger(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse("ssap://audio/getStatus", webostv.Payload{"volume": 9})
+
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := new(testLogger)
+	tv.SetLogger(l)
+	go tv.MessageHandler()
+	key, err := tv.Register("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tv.Register(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tv.AudioGetStatus()
+	tv.Request("ssap://tv/getCurrentChannel", nil)
+	tv.Close()
+
+	l.Lock()
+	defer l.Unlock()
+	var sent, received, requests, failed int
+	for _, r := range l.records {
+		if payload, ok := r.keyvals["payload"]; ok && strings.Contains(fmt.Sprintf("%s", payload), key) {
+			t.Errorf("client key not redacted: %v", r.keyvals)
+		}
+		switch r.msg {
+		case "ssap message":
+			if r.keyvals["dir"] == webostv.FrameSent {
+				sent++
+			} else {
+				received++
+			}
+		case "ssap request":
+			requests++
+			if r.keyvals["id"] == "" || r.keyvals["latency"].(time.Duration) <= 0 {
+				t.Errorf("unexpected request record: %v", r.keyvals)
+			}
+			if r.keyvals["error"] != nil {
+				failed++
+				if r.keyvals["uri"] != "ssap://tv/getCurrentChannel" {
+					t.Errorf("unexpected failed request: %v", r.keyvals)
+				}
+			}
+		}
+	}
+	if sent != 4 || received != 5 || requests != 2 || failed != 1 {
+		t.Errorf("unexpected records: %d sent, %d received, %d requests, %d failed",
+			sent, received, requests, failed)
+	}
+}
@@ -1,7 +1,7 @@
 package webostv
 
 import (
-	"github.com/mitchellh/mapstructure"
+	"context"
 )
 
 type App struct {
@@ -73,19 +73,6 @@ type App struct {
 	// "keyFilterTable": [
 }
 
-func (tv *Tv) ApplicationManagerGetAppInfo(id string) (info App, err error) {
-	// {"type":"response","id":"YokZ11MX","payload":{"mute":false,"returnValue":true}}
-	var resp struct {
-		AppInfo App
-		AppId   string
-	}
-	err = tv.RequestResponseParam("ssap://com.webos.applicationManager/getAppInfo",
-		Payload{"id": id},
-		&resp)
-
-	return resp.AppInfo, err
-}
-
 type ForegroundAppInfo struct {
 	AppId     string
 	WindowId  string
@@ -96,24 +83,15 @@ func (i *ForegroundAppInfo) IsLiveTv() bool {
 	return i.AppId == "com.webos.app.livetv"
 }
 
-func (tv *Tv) ApplicationManagerGetForegroundAppInfo() (info ForegroundAppInfo, err error) {
-	err = tv.RequestResponseParam("ssap://com.webos.applicationManager/getForegroundAppInfo", nil, &info)
-	// {"type":"response","id":"CyvqdwSl","payload":{"appId":"com.webos.app.hdmi2","returnValue":true,"windowId":"","processId":"n-1059"}}
-	return info, err
-}
-
-func (tv *Tv) ApplicationManagerMonitorForegroundAppInfo(process func(info ForegroundAppInfo) error, quit <-chan struct{}) error {
-	return tv.MonitorStatus("ssap://com.webos.applicationManager/getForegroundAppInfo", nil, func(payload Payload) (err error) {
-		var info ForegroundAppInfo
-		err = mapstructure.Decode(payload, &info)
-		if err == nil {
-			err = process(info)
-		}
-		return err
-	}, quit)
+func (tv *Tv) ApplicationManagerLaunch(id string, params Payload) (processId string, err error) {
+	return tv.ApplicationManagerLaunchContext(context.Background(), id, params)
 }
 
-func (tv *Tv) ApplicationManagerLaunch(id string, params Payload) (processId string, err error) {
+func (tv *Tv) ApplicationManagerLaunchContext(ctx context.Context, id string, params Payload) (processId string, err error) {
+	err = tv.checkSupported(UriApplicationManagerLaunch)
+	if err != nil {
+		return processId, err
+	}
 	// {"type":"response","id":"IkVU1ZGv","payload":{"returnValue":true,"processId":"1001"}}
 	p := make(Payload)
 	p["id"] = id
@@ -123,19 +101,11 @@ func (tv *Tv) ApplicationManagerLaunch(id string, params Payload) (processId str
 	var resp struct {
 		ProcessId string
 	}
-	err = tv.RequestResponseParam("ssap://com.webos.applicationManager/launch", p, &resp)
+	err = tv.RequestResponseParamContext(ctx, UriApplicationManagerLaunch, p, &resp)
 
 	return resp.ProcessId, err
 }
 
-func (tv *Tv) ApplicationManagerListApps() (list []App, err error) {
-	var resp struct {
-		Apps []App
-	}
-	err = tv.RequestResponseParam("ssap://com.webos.applicationManager/listApps", nil, &resp)
-	return resp.Apps, err
-}
-
 type LaunchPoint struct {
 	Removable       bool              // "removable": false,
 	LargeIcon       string            // "largeIcon": "/mnt/otncabi/usr/palm/applications/com.webos.app.discovery/lgstore_130x130.png",
@@ -163,33 +133,51 @@ type CaseDetail struct {
 }
 
 func (tv *Tv) ApplicationManagerListLaunchPoints() (launchPoints []LaunchPoint, caseDetail CaseDetail, err error) {
+	return tv.ApplicationManagerListLaunchPointsContext(context.Background())
+}
+
+func (tv *Tv) ApplicationManagerListLaunchPointsContext(ctx context.Context) (launchPoints []LaunchPoint, caseDetail CaseDetail, err error) {
+	err = tv.checkSupported(UriApplicationManagerListLaunchPoints)
+	if err != nil {
+		return launchPoints, caseDetail, err
+	}
 	var resp struct {
 		Subscribed   bool
 		LaunchPoints []LaunchPoint
 		CaseDetail   CaseDetail
 	}
-	err = tv.RequestResponseParam("ssap://com.webos.applicationManager/listLaunchPoints", nil, &resp)
+	err = tv.RequestResponseParamContext(ctx, UriApplicationManagerListLaunchPoints, nil, &resp)
 	return resp.LaunchPoints, resp.CaseDetail, err
 }
-func (tv *Tv) SystemLauncherClose(sessionId string) (err error) {
-	_, err = tv.Request("ssap://system.launcher/close",
-		Payload{"sessionId": sessionId})
-	return err
+func (tv *Tv) SystemLauncherGetAppState(sessionId string) (running, visible bool, err error) {
+	return tv.SystemLauncherGetAppStateContext(context.Background(), sessionId)
 }
 
-func (tv *Tv) SystemLauncherGetAppState(sessionId string) (running, visible bool, err error) {
+func (tv *Tv) SystemLauncherGetAppStateContext(ctx context.Context, sessionId string) (running, visible bool, err error) {
+	err = tv.checkSupported(UriSystemLauncherGetAppState)
+	if err != nil {
+		return running, visible, err
+	}
 	// {"running":true,"visible":true,"returnValue":true}
 	var resp struct {
 		Running bool
 		Visible bool
 	}
-	err = tv.RequestResponseParam("ssap://system.launcher/getAppState",
+	err = tv.RequestResponseParamContext(ctx, UriSystemLauncherGetAppState,
 		Payload{"sessionId": sessionId}, &resp)
 
 	return resp.Running, resp.Visible, err
 }
 
 func (tv *Tv) SystemLauncherLaunch(appId string, params Payload) (sessionId string, err error) {
+	return tv.SystemLauncherLaunchContext(context.Background(), appId, params)
+}
+
+func (tv *Tv) SystemLauncherLaunchContext(ctx context.Context, appId string, params Payload) (sessionId string, err error) {
+	err = tv.checkSupported(UriSystemLauncherLaunch)
+	if err != nil {
+		return sessionId, err
+	}
 	// {"returnValue":true,"sessionId":"eW91dHViZS5sZWFuYmFjay52NDp1bmRlZmluZWQ="}
 	p := make(Payload)
 	p["id"] = appId
@@ -199,18 +187,26 @@ func (tv *Tv) SystemLauncherLaunch(appId string, params Payload) (sessionId stri
 	var resp struct {
 		SessionId string
 	}
-	err = tv.RequestResponseParam("ssap://system.launcher/launch", p, &resp)
+	err = tv.RequestResponseParamContext(ctx, UriSystemLauncherLaunch, p, &resp)
 
 	return resp.SessionId, err
 }
 
 func (tv *Tv) SystemLauncherOpen(url string) (appId, sessionId string, err error) {
+	return tv.SystemLauncherOpenContext(context.Background(), url)
+}
+
+func (tv *Tv) SystemLauncherOpenContext(ctx context.Context, url string) (appId, sessionId string, err error) {
+	err = tv.checkSupported(UriSystemLauncherOpen)
+	if err != nil {
+		return appId, sessionId, err
+	}
 	// {"returnValue":true,"id":"com.webos.app.browser","sessionId":"Y29tLndlYm9zLmFwcC5icm93c2VyOnVuZGVmaW5lZA=="}
 	var resp struct {
 		Id        string
 		SessionId string
 	}
-	err = tv.RequestResponseParam("ssap://system.launcher/open",
+	err = tv.RequestResponseParamContext(ctx, UriSystemLauncherOpen,
 		Payload{"target": url}, &resp)
 
 	return resp.Id, resp.SessionId, err
@@ -218,15 +214,23 @@ func (tv *Tv) SystemLauncherOpen(url string) (appId, sessionId string, err error
 
 /*
 func (tv *Tv) LaunchBrowser(url string) (sessionId string, err error) {
+	return tv.LaunchBrowserContext(context.Background(), url)
+}
+
+func (tv *Tv) LaunchBrowserContext(ctx context.Context, url string) (sessionId string, err error) {
 	var p Payload
 	if url != "" {
 		p = Payload{"target": url}
 	}
-	return tv.SystemLauncherLaunch("com.webos.app.browser", p)
+	return tv.SystemLauncherLaunchContext(ctx, "com.webos.app.browser", p)
 }
 */
 
 func (tv *Tv) LaunchYoutube(videoId string) (sessionId string, err error) {
+	return tv.LaunchYoutubeContext(context.Background(), videoId)
+}
+
+func (tv *Tv) LaunchYoutubeContext(ctx context.Context, videoId string) (sessionId string, err error) {
 	var p Payload
 	if videoId != "" {
 		p = Payload{
@@ -235,15 +239,19 @@ func (tv *Tv) LaunchYoutube(videoId string) (sessionId string, err error) {
 			},
 		}
 	}
-	return tv.SystemLauncherLaunch("youtube.leanback.v4", p)
+	return tv.SystemLauncherLaunchContext(ctx, "youtube.leanback.v4", p)
 }
 
 func (tv *Tv) LaunchNetflix(contentId string) (sessionId string, err error) {
+	return tv.LaunchNetflixContext(context.Background(), contentId)
+}
+
+func (tv *Tv) LaunchNetflixContext(ctx context.Context, contentId string) (sessionId string, err error) {
 	var p Payload
 	if contentId != "" {
 		p = Payload{
 			"contentId": "m=http%3A%2F%2Fapi.netflix.com%2Fcatalog%2Ftitles%2Fmovies%2F" + contentId + "&source_type=4",
 		}
 	}
-	return tv.SystemLauncherLaunch("netflix", p)
+	return tv.SystemLauncherLaunchContext(ctx, "netflix", p)
 }
@@ -1,12 +1,18 @@
 package webostv
 
-func (tv *Tv) MediaViewerClose(sessionId string) (err error) {
-	_, err = tv.Request("ssap://media.viewer/close",
-		Payload{"sessionId": sessionId})
-	return err
-}
+import (
+	"context"
+)
 
 func (tv *Tv) MediaViewerOpen(url, title, description, mimeType, iconSrc string, loop bool) (appId, sessionId string, err error) {
+	return tv.MediaViewerOpenContext(context.Background(), url, title, description, mimeType, iconSrc, loop)
+}
+
+func (tv *Tv) MediaViewerOpenContext(ctx context.Context, url, title, description, mimeType, iconSrc string, loop bool) (appId, sessionId string, err error) {
+	err = tv.checkSupported(UriMediaViewerOpen)
+	if err != nil {
+		return appId, sessionId, err
+	}
 	// {"returnValue":true,"id":"com.webos.app.tvsimpleviewer","sessionId":"Y29tLndlYm9zLmFwcC50dnNpbXBsZXZpZXdlcjp1bmRlZmluZWQ="}
 
 	p := make(Payload)
@@ -30,7 +36,7 @@ func (tv *Tv) MediaViewerOpen(url, title, description, mimeType, iconSrc string,
 		Id        string
 		SessionId string
 	}
-	err = tv.RequestResponseParam("ssap://media.viewer/open", p, &resp)
+	err = tv.RequestResponseParamContext(ctx, UriMediaViewerOpen, p, &resp)
 
 	return resp.Id, resp.SessionId, err
 }
@@ -1,74 +1,25 @@
 package webostv
 
+import (
+	"context"
+)
+
 type ServiceListEntry struct {
 	Name    string
 	Version int
 }
 
-func (tv *Tv) ApiGetServiceList() (list []ServiceListEntry, err error) {
-	// "payload":{"services":[{"name":"api","version":1},{"name":"audio","version":1},{"name":"media.controls","version":1},{"name":"media.viewer","version":1},{"name":"pairing","version":1},{"name":"system","version":1},{"name":"system.launcher","version":1},{"name":"system.notifications","version":1},{"name":"tv","version":1},{"name":"webapp","version":2}],"returnValue":true}
-	var resp struct {
-		Services []ServiceListEntry
-	}
-	err = tv.RequestResponseParam("ssap://api/getServiceList", nil, &resp)
-	return resp.Services, err
-}
-
-// TODO ssap://com.webos.service.apiadapter/audio/changeSoundOutput
-// TODO ssap://com.webos.service.apiadapter/audio/getSoundOutput // 404 no such service or method
-// TODO ssap://com.webos.service.appstatus/getAppStatus // 404 no such service or method
-// TODO ssap://com.webos.service.bluetooth/gap/findDevices
-// TODO ssap://com.webos.service.bluetooth/gap/getTrustedDevices
-// TODO ssap://com.webos.service.bluetooth/gap/isWiFiOnly
-// TODO ssap://com.webos.service.bluetooth/gap/removeTrustedDevice
-// TODO ssap://com.webos.service.bluetooth/service/connect
-// TODO ssap://com.webos.service.bluetooth/service/disconnect
-// TODO ssap://com.webos.service.bluetooth/service/getStates
-// TODO ssap://com.webos.service.bluetooth/service/subscribeNotifications
-// TODO ssap://com.webos.service.connectionmanager/getinfo // 404 no such service or method
-
-func (tv *Tv) ImeDeleteCharacters(count int) (err error) {
-	_, err = tv.Request("ssap://com.webos.service.ime/deleteCharacters",
-		Payload{"count": count})
-	return err
-}
-
-func (tv *Tv) ImeInsertText(text string, replace bool) (err error) {
-	_, err = tv.Request("ssap://com.webos.service.ime/insertText",
-		Payload{
-			"text":    text,
-			"replace": replace,
-		})
-	return err
-}
-
-// TODO ssap://com.webos.service.ime/registerRemoteKeyboard
-
-// {"type":"response","id":"nlHxhqwT","payload":{"currentWidget":{"autoCapitalizationEnabled":true,"contentType":"text","correctionEnabled":false,"cursorPosition":13,"focus":true,"hasSurroundingText":true,"hiddenText":false,"predictionEnabled":true,"surroundingTextLength":13},"focusChanged":true}}
-
-func (tv *Tv) ImeSendEnterKey() (err error) {
-	_, err = tv.Request("ssap://com.webos.service.ime/sendEnterKey", nil)
-	return err
+func (tv *Tv) SdxGetHttpHeaderForServiceRequest() (resp map[string]string, err error) {
+	return tv.SdxGetHttpHeaderForServiceRequestContext(context.Background())
 }
 
-// TODO ssap://com.webos.service.miracast/close
-// TODO ssap://com.webos.service.miracast/getConnectionStatus
-// TODO ssap://com.webos.service.miracast/getP2pState
-// TODO ssap://com.webos.service.miracast/setUACSettings
-// TODO ssap://com.webos.service.miracast/uibc/getUibcKeyEvent
-
-func (tv *Tv) GetPointerInputSocket() (socketPath string, err error) {
-	// "payload":{"returnValue":true,"scenario":"mastervolume_tv_speaker","volume":9,"muted":false}
-	var resp struct {
-		SocketPath string
+func (tv *Tv) SdxGetHttpHeaderForServiceRequestContext(ctx context.Context) (resp map[string]string, err error) {
+	err = tv.checkSupported(UriSdxGetHttpHeaderForServiceRequest)
+	if err != nil {
+		return resp, err
 	}
-	err = tv.RequestResponseParam("ssap://com.webos.service.networkinput/getPointerInputSocket", nil, &resp)
-	return resp.SocketPath, err
-}
-
-func (tv *Tv) SdxGetHttpHeaderForServiceRequest() (resp map[string]string, err error) {
 	// {"clearedForDuty":true,"returnValue":true}
-	tmpresp, err := tv.Request("ssap://com.webos.service.sdx/getHttpHeaderForServiceRequest", nil)
+	tmpresp, err := tv.RequestContext(ctx, UriSdxGetHttpHeaderForServiceRequest, nil)
 	if tmpresp != nil {
 		resp = make(map[string]string)
 	}
@@ -81,16 +32,15 @@ func (tv *Tv) SdxGetHttpHeaderForServiceRequest() (resp map[string]string, err e
 	return resp, err
 }
 
-func (tv *Tv) SecondscreenGatewayTestSecure() (clearedForDuty bool, err error) {
-	// {"clearedForDuty":true,"returnValue":true}
-	var resp struct {
-		ClearedForDuty bool
-	}
-	err = tv.RequestResponseParam("ssap://com.webos.service.secondscreen.gateway/test/secure", nil, &resp)
-	return resp.ClearedForDuty, err
+func (tv *Tv) Get3DStatus() (status bool, pattern string, err error) {
+	return tv.Get3DStatusContext(context.Background())
 }
 
-func (tv *Tv) Get3DStatus() (status bool, pattern string, err error) {
+func (tv *Tv) Get3DStatusContext(ctx context.Context) (status bool, pattern string, err error) {
+	err = tv.checkSupported(UriGet3DStatus)
+	if err != nil {
+		return status, pattern, err
+	}
 	// {"returnValue":true,"status3D":{"status":true,"pattern":"2dto3d"}
 	var resp struct {
 		Status3D struct {
@@ -98,25 +48,19 @@ func (tv *Tv) Get3DStatus() (status bool, pattern string, err error) {
 			Pattern string
 		}
 	}
-	err = tv.RequestResponseParam("ssap://com.webos.service.tv.display/get3DStatus", nil, &resp)
+	err = tv.RequestResponseParamContext(ctx, UriGet3DStatus, nil, &resp)
 	return resp.Status3D.Status, resp.Status3D.Pattern, err
 }
 
-func (tv *Tv) Set3DOff() (err error) {
-	_, err = tv.Request("ssap://com.webos.service.tv.display/set3DOff", nil)
-	return err
-}
-
-func (tv *Tv) Set3DOn() (err error) {
-	_, err = tv.Request("ssap://com.webos.service.tv.display/set3DOn", nil)
-	return err
+func (tv *Tv) GetCurrentTime() (y, m, d, h, min, s int, err error) {
+	return tv.GetCurrentTimeContext(context.Background())
 }
 
-// TODO ssap://com.webos.service.tv.keymanager/listInterestingEvents // error KEYMANAGER_ERROR_0001: Required parameter does not exist - subscribe
-// TODO ssap://com.webos.service.tvpower/power/getPowerState // 404 no such service or method
-// TODO ssap://com.webos.service.tvpower/power/turnOnScreen // 404 no such service or method
-
-func (tv *Tv) GetCurrentTime() (y, m, d, h, min, s int, err error) {
+func (tv *Tv) GetCurrentTimeContext(ctx context.Context) (y, m, d, h, min, s int, err error) {
+	err = tv.checkSupported(UriGetCurrentTime)
+	if err != nil {
+		return y, m, d, h, min, s, err
+	}
 	var resp struct {
 		Year   int
 		Month  int
@@ -125,7 +69,7 @@ func (tv *Tv) GetCurrentTime() (y, m, d, h, min, s int, err error) {
 		Minute int
 		Second int
 	}
-	err = tv.RequestResponseParam("ssap://com.webos.service.tv.time/getCurrentTime", nil, &resp)
+	err = tv.RequestResponseParamContext(ctx, UriGetCurrentTime, nil, &resp)
 	return resp.Year, resp.Month, resp.Day, resp.Hour, resp.Minute, resp.Second, err
 }
 
@@ -144,20 +88,6 @@ type CurrentSWInformation struct {
 	LanguageCode  string `mapstructure:"language_code"`  // "language_code":"en-GB"}
 }
 
-func (tv *Tv) GetCurrentSWInformation() (info CurrentSWInformation, err error) {
-	err = tv.RequestResponseParam("ssap://com.webos.service.update/getCurrentSWInformation", nil, &info)
-	return info, err
-}
-
-// TODO ssap://com.webos.service.update/getProgress
-// TODO ssap://com.webos.service.update/getStatus
-// TODO ssap://com.webos.service.update/startUpdateByRemoteApp
-// TODO ssap://config/getConfigs // 404 no such service or method
-
-// TODO ssap://pairing/setPin
-// TODO ssap://settings/getSystemSettings // 404 no such service or method
-// TODO ssap://system/getHostMessage // 404 no such service or method
-
 type SystemInfo struct {
 	// {"type":"response","id":"e8soy4EW","payload":{"features":{"3d":true,"dvr":true},"receiverType":"dvb","modelName":"42LB650V-ZN","returnValue":true}}
 	Features     map[string]bool
@@ -165,38 +95,14 @@ type SystemInfo struct {
 	ModelName    string
 }
 
-func (tv *Tv) SystemGetSystemInfo() (info SystemInfo, err error) {
-	err = tv.RequestResponseParam("ssap://system/getSystemInfo", nil, &info)
-	return info, err
-}
-
-// TODO ssap://system.notifications/createAlert // 404 no such service or method
-
-func (tv *Tv) SystemNotificationsCreateToast(msg string) (toastId string, err error) {
-	// {"toastId":"com.webos.service.apiadapter-1522334066285","returnValue":true}
-	var resp struct {
-		ToastId string
-	}
-	err = tv.RequestResponseParam("ssap://system.notifications/createToast",
-		Payload{"message": msg}, &resp)
-
-	return resp.ToastId, err
+type PowerState struct {
+	State         string // "state": "Active", "Active Standby", "Screen Off" or "Suspend"
+	Processing    string // "processing": "Request Power Off"
+	PowerOnReason string // "powerOnReason": "remoteKey"
 }
 
-func (tv *Tv) SystemTurnOff() (err error) {
-	_, err = tv.Request("ssap://system/turnOff", nil)
-	return err
+// IsOn tells if the TV is on with the screen on. It is false in the
+// standby and screen off states and while the TV is turning off.
+func (ps *PowerState) IsOn() bool {
+	return ps.State == "Active" && ps.Processing == ""
 }
-
-// TODO ssap://timer/getSettings
-// TODO ssap://timer/setSettings
-// TODO ssap://user/resetUserInfo
-// TODO ssap://user/setUserData
-// TODO ssap://user/setUserInfo // 404 no such service or method
-// TODO ssap://user/setUserSchedule
-// TODO ssap://webapp/closeWebApp
-// TODO ssap://webapp/connectToApp
-// TODO ssap://webapp/isWebAppPinned
-// TODO ssap://webapp/launchWebApp
-// TODO ssap://webapp/pinWebApp
-// TODO ssap://webapp/removePinnedWebApp
@@ -1,21 +1,9 @@
 package webostv
 
 import (
-	"github.com/mitchellh/mapstructure"
+	"context"
 )
 
-func (tv *Tv) TvChannelDown() (err error) {
-	_, err = tv.Request("ssap://tv/channelDown", nil)
-	return err
-}
-
-func (tv *Tv) TvChannelUp() (err error) {
-	_, err = tv.Request("ssap://tv/channelUp", nil)
-	return err
-}
-
-// TODO ssap://tv/getACRAuthToken // 401 insufficient permissions
-
 type TvCurrentProgramInfo struct {
 	ProgramId      string // "programId": "0_31_13105_42559",
 	ProgramName    string // "programName": "Keno ja Synttärit",
@@ -32,6 +20,14 @@ type TvCurrentProgramInfo struct {
 }
 
 func (tv *Tv) TvGetChannelCurrentProgramInfo(channelId string) (info TvCurrentProgramInfo, err error) {
+	return tv.TvGetChannelCurrentProgramInfoContext(context.Background(), channelId)
+}
+
+func (tv *Tv) TvGetChannelCurrentProgramInfoContext(ctx context.Context, channelId string) (info TvCurrentProgramInfo, err error) {
+	err = tv.checkSupported(UriTvGetChannelCurrentProgramInfo)
+	if err != nil {
+		return info, err
+	}
 	var payload Payload
 	if channelId != "" {
 		payload = Payload{
@@ -39,7 +35,7 @@ func (tv *Tv) TvGetChannelCurrentProgramInfo(channelId string) (info TvCurrentPr
 		}
 
 	}
-	err = tv.RequestResponseParam("ssap://tv/getChannelCurrentProgramInfo", payload, &info)
+	err = tv.RequestResponseParamContext(ctx, UriTvGetChannelCurrentProgramInfo, payload, &info)
 	return info, err
 }
 
@@ -96,14 +92,6 @@ type TvChannel struct {
 	// "CASystemIDListCount": 0, // ???
 }
 
-func (tv *Tv) TvGetChannelList() (list []TvChannel, err error) {
-	var resp struct {
-		ChannelList []TvChannel
-	}
-	err = tv.RequestResponseParam("ssap://tv/getChannelList", nil, &resp)
-	return resp.ChannelList, err
-}
-
 type TvProgramRating struct {
 	Id           string `mapstructure:"_id"` // "_id": "157ac",
 	RatingString string // "ratingString": "",
@@ -128,6 +116,14 @@ type TvProgram struct {
 }
 
 func (tv *Tv) TvGetChannelProgramInfo(channelId string) (channel TvChannel, programlist []TvProgram, err error) {
+	return tv.TvGetChannelProgramInfoContext(context.Background(), channelId)
+}
+
+func (tv *Tv) TvGetChannelProgramInfoContext(ctx context.Context, channelId string) (channel TvChannel, programlist []TvProgram, err error) {
+	err = tv.checkSupported(UriTvGetChannelProgramInfo)
+	if err != nil {
+		return channel, programlist, err
+	}
 	var resp struct {
 		Channel     TvChannel
 		ProgramList []TvProgram
@@ -139,7 +135,7 @@ func (tv *Tv) TvGetChannelProgramInfo(channelId string) (channel TvChannel, prog
 		}
 
 	}
-	err = tv.RequestResponseParam("ssap://tv/getChannelProgramInfo", payload, &resp)
+	err = tv.RequestResponseParamContext(ctx, UriTvGetChannelProgramInfo, payload, &resp)
 	return resp.Channel, resp.ProgramList, err
 }
 
@@ -167,22 +163,6 @@ type TvCurrentChannel struct {
 	// "dualChannelNumber":null
 }
 
-func (tv *Tv) TvGetCurrentChannel() (cur TvCurrentChannel, err error) {
-	err = tv.RequestResponseParam("ssap://tv/getCurrentChannel", nil, &cur)
-	return cur, err
-}
-
-func (tv *Tv) TvMonitorCurrentChannel(process func(cur TvCurrentChannel) error, quit <-chan struct{}) error {
-	return tv.MonitorStatus("ssap://tv/getCurrentChannel", nil, func(payload Payload) (err error) {
-		var cur TvCurrentChannel
-		err = mapstructure.Decode(payload, &cur)
-		if err == nil {
-			err = process(cur)
-		}
-		return err
-	}, quit)
-}
-
 type TvExternalInput struct {
 	Id              string // "id": "SCART_1",
 	Label           string // "label": "AV1",
@@ -197,29 +177,3 @@ type TvExternalInput struct {
 	// "subList": [],
 	// "subCount": 0,
 }
-
-func (tv *Tv) TvGetExternalInputList() (list []TvExternalInput, err error) {
-	var resp struct {
-		Devices []TvExternalInput
-	}
-
-	err = tv.RequestResponseParam("ssap://tv/getExternalInputList", nil, &resp)
-	return resp.Devices, err
-}
-
-func (tv *Tv) TvOpenChannelId(channelId string) (err error) {
-	_, err = tv.Request("ssap://tv/openChannel",
-		Payload{"channelId": channelId})
-	return err
-}
-
-func (tv *Tv) TvOpenChannelNumber(channelNumber string) (err error) {
-	_, err = tv.Request("ssap://tv/openChannel",
-		Payload{"channelNumber": channelNumber})
-	return err
-}
-
-func (tv *Tv) TvSwitchInput(inputId string) (err error) {
-	_, err = tv.Request("ssap://tv/switchInput", Payload{"inputId": inputId})
-	return err
-}
@@ -1,58 +1,208 @@
 package webostv
 
 import (
+	"context"
 	"fmt"
 	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"net/url"
 	"sync"
 )
 
+var ErrPointerSocketClosed = errors.New("pointer socket is closed")
+
 type PointerSocket struct {
-	Address string
-	ws      *websocket.Conn
+	Address  string
+	ws       *websocket.Conn
+	recorder *Recorder
+	tv       *Tv  // set if the socket is managed by Tv.Pointer
+	closed   bool // Close has been called
+	down     bool // pointer button is held down
+	x, y     int  // pointer position relative to the initial position
 	sync.Mutex
 }
 
 func (dialer *Dialer) DialPointerSocket(address string) (ps *PointerSocket, err error) {
+	return dialer.DialPointerSocketContext(context.Background(), address)
+}
+
+// DialPointerSocketContext is like DialPointerSocket but honours ctx. If
+// the socket uses TLS, the certificate is verified against the
+// fingerprint pinned for the host of address.
+func (dialer *Dialer) DialPointerSocketContext(ctx context.Context, address string) (ps *PointerSocket, err error) {
+	var expected string
+	if u, err := url.Parse(address); err == nil {
+		expected = dialer.pinnedFingerprint(u.Hostname())
+	}
+	return dialer.dialPointerSocketExpect(ctx, address, expected)
+}
+
+func (dialer *Dialer) dialPointerSocketExpect(ctx context.Context, address, expected string) (ps *PointerSocket, err error) {
+	ws, err := dialer.dialPointerSocket(ctx, address, expected)
+	if err != nil {
+		return nil, err
+	}
+	return &PointerSocket{
+		Address: address,
+		ws:      ws,
+	}, nil
+}
+
+func (dialer *Dialer) dialPointerSocket(ctx context.Context, address, expected string) (ws *websocket.Conn, err error) {
 	wsDialer := dialer.WebsocketDialer
 	if wsDialer == nil {
 		wsDialer = websocket.DefaultDialer
 	}
-	ws, resp, err := wsDialer.Dial(address, nil)
+	ws, resp, err := wsDialer.DialContext(ctx, address, nil)
 	if err != nil {
 		return nil, err
 	}
 	err = resp.Body.Close()
 	if err != nil {
+		ws.Close()
 		return nil, err
 	}
-
-	return &PointerSocket{
-		Address: address,
-		ws:      ws,
-	}, nil
+	_, err = verifyFingerprint(ws, address, expected)
+	if err != nil {
+		ws.Close()
+		return nil, err
+	}
+	return ws, nil
 }
 
 func (tv *Tv) NewPointerSocket() (ps *PointerSocket, err error) {
-	socketPath, err := tv.GetPointerInputSocket()
+	return tv.NewPointerSocketContext(context.Background())
+}
+
+func (tv *Tv) NewPointerSocketContext(ctx context.Context) (ps *PointerSocket, err error) {
+	socketPath, err := tv.GetPointerInputSocketContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ps, err = tv.auxDialer().dialPointerSocketExpect(ctx, socketPath, tv.Fingerprint())
 	if err != nil {
 		return nil, err
 	}
-	return DefaultDialer.DialPointerSocket(socketPath)
+	ps.recorder = tv.recorder
+	return ps, nil
+}
+
+// auxDialer returns the Dialer which is used for the auxiliary sockets of
+// tv, such as the pointer socket. It is the Dialer which dialed tv, so
+// its TLS configuration and proxy settings apply to them as well. The
+// auxiliary sockets are verified against the certificate fingerprint of
+// the main connection.
+func (tv *Tv) auxDialer() *Dialer {
+	if tv.dialer == nil {
+		return &DefaultDialer
+	}
+	return tv.dialer
+}
+
+// Pointer returns the pointer socket managed by tv. It connects lazily on
+// first use. If the connection is lost, a new socket path is requested
+// from the TV and the socket is reconnected on the next call, which is
+// then retried once. The MessageHandler of the managed socket is run
+// internally and must not be called. Tv.Close closes the managed socket.
+func (tv *Tv) Pointer() *PointerSocket {
+	tv.wsMutex.Lock()
+	defer tv.wsMutex.Unlock()
+	if tv.pointer == nil {
+		tv.pointer = &PointerSocket{tv: tv, recorder: tv.recorder}
+		if tv.closed {
+			tv.pointer.closed = true
+		}
+	}
+	return tv.pointer
 }
 
 func (ps *PointerSocket) MessageHandler() (err error) {
+	ps.Lock()
+	ws := ps.ws
+	ps.Unlock()
+	if ws == nil {
+		return ErrPointerSocketClosed
+	}
+	return ps.readMessages(ws)
+}
+
+func (ps *PointerSocket) readMessages(ws *websocket.Conn) (err error) {
 	for {
-		_, _, err = ps.ws.ReadMessage()
+		messageType, p, err := ws.ReadMessage()
 		if err != nil {
 			return err
 		}
+		ps.record(FrameReceived, messageType, p)
 	}
 	// not reached
 }
 
+func (ps *PointerSocket) record(dir string, messageType int, data []byte) {
+	if ps.recorder != nil {
+		ps.recorder.RecordMessage(dir, SocketPointer, messageType, data)
+	}
+}
+
+// handleManaged runs the message handler of a managed socket and drops
+// the connection when it fails so that the next write reconnects.
+func (ps *PointerSocket) handleManaged(ws *websocket.Conn) {
+	err := ps.readMessages(ws)
+	ps.Lock()
+	if ps.ws == ws {
+		ps.tv.logWarn("pointer socket lost", "error", err)
+		ps.ws.Close()
+		ps.ws = nil
+	}
+	ps.Unlock()
+}
+
+// conn returns the connection of a managed socket, connecting it if
+// needed. The socket path is requested and the socket is dialed without
+// holding the lock, so Close and the other writers are not blocked by
+// them.
+func (ps *PointerSocket) conn(ctx context.Context) (ws *websocket.Conn, err error) {
+	ps.Lock()
+	closed, ws := ps.closed, ps.ws
+	ps.Unlock()
+	if closed {
+		return nil, ErrPointerSocketClosed
+	}
+	if ws != nil {
+		return ws, nil
+	}
+
+	ctx, cancel := withDefaultTimeout(ctx, Timeout)
+	defer cancel()
+	socketPath, err := ps.tv.GetPointerInputSocketContext(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "pointer socket")
+	}
+	ws, err = ps.tv.auxDialer().dialPointerSocket(ctx, socketPath, ps.tv.Fingerprint())
+	if err != nil {
+		return nil, errors.Wrap(err, "pointer socket")
+	}
+
+	ps.Lock()
+	defer ps.Unlock()
+	if ps.closed {
+		ws.Close()
+		return nil, ErrPointerSocketClosed
+	}
+	if ps.ws != nil {
+		// another writer connected meanwhile
+		ws.Close()
+		return ps.ws, nil
+	}
+	ps.Address = socketPath
+	ps.ws = ws
+	go ps.handleManaged(ws)
+	return ws, nil
+}
+
 func (ps *PointerSocket) Close() (err error) {
 	ps.Lock()
 	defer ps.Unlock()
+	ps.closed = true
 	if ps.ws != nil {
 		err = ps.ws.Close()
 		ps.ws = nil
@@ -60,30 +210,119 @@ func (ps *PointerSocket) Close() (err error) {
 	return err
 }
 
-func (ps *PointerSocket) writeMessage(messageType int, data []byte) error {
-	ps.Lock()
-	defer ps.Unlock()
-	return ps.ws.WriteMessage(messageType, data)
+// write sends a message. Managed sockets are (re)connected as needed and
+// the message is sent again on a fresh connection if sending fails. The
+// caller must not hold the lock.
+func (ps *PointerSocket) write(ctx context.Context, messageType int, data []byte) (err error) {
+	if ctx.Err() != nil {
+		return contextError(ctx)
+	}
+	if ps.tv == nil {
+		ps.Lock()
+		defer ps.Unlock()
+		if ps.ws == nil {
+			return ErrPointerSocketClosed
+		}
+		return ps.send(ctx, ps.ws, messageType, data)
+	}
+	for retry := 0; ; retry++ {
+		ws, err := ps.conn(ctx)
+		if err != nil {
+			return err
+		}
+		ps.Lock()
+		switch {
+		case ps.closed:
+			err = ErrPointerSocketClosed
+		case ps.ws != ws:
+			err = errors.New("pointer socket lost")
+		default:
+			err = ps.send(ctx, ws, messageType, data)
+			if err != nil {
+				ps.tv.logWarn("pointer socket write failed", "error", err)
+				ws.Close()
+				ps.ws = nil
+			}
+		}
+		ps.Unlock()
+		if err == nil || err == ErrPointerSocketClosed || retry > 0 {
+			return err
+		}
+	}
+}
+
+// send writes a message to ws. The caller must hold the lock.
+func (ps *PointerSocket) send(ctx context.Context, ws *websocket.Conn, messageType int, data []byte) error {
+	deadline, _ := ctx.Deadline() // zero value means no deadline
+	ws.SetWriteDeadline(deadline)
+	err := ws.WriteMessage(messageType, data)
+	if err == nil {
+		ps.record(FrameSent, messageType, data)
+	}
+	return err
 }
 
+// Input sends an input message of type btype. See Button and Press for the
+// names of the buttons.
 func (ps *PointerSocket) Input(btype, bname string) (err error) {
-	msg := "type:" + btype + "\n" + "name:" + bname + "\n\n"
-	return ps.writeMessage(websocket.TextMessage, []byte(msg))
+	return ps.InputContext(context.Background(), btype, bname)
 }
 
-// UP DOWN LEFT RIGHT HOME BACK DASH  and numbers
+// InputContext is like Input but honours ctx.
+func (ps *PointerSocket) InputContext(ctx context.Context, btype, bname string) (err error) {
+	msg := "type:" + btype + "\n" + "name:" + bname + "\n\n"
+	return ps.write(ctx, websocket.TextMessage, []byte(msg))
+}
 
 func (ps *PointerSocket) Move(dx, dy int) (err error) {
-	msg := fmt.Sprintf("type:move\ndx:%d\ndy:%d\ndown:0\n\n", dx, dy)
-	return ps.writeMessage(websocket.TextMessage, []byte(msg))
+	return ps.MoveContext(context.Background(), dx, dy)
+}
+
+// MoveContext is like Move but honours ctx.
+func (ps *PointerSocket) MoveContext(ctx context.Context, dx, dy int) (err error) {
+	err = ps.writePointer(ctx, "move", dx, dy)
+	if err == nil {
+		ps.Lock()
+		ps.x += dx
+		ps.y += dy
+		ps.Unlock()
+	}
+	return err
 }
 
 func (ps *PointerSocket) Scroll(dx, dy int) (err error) {
-	msg := fmt.Sprintf("type:scroll\ndx:%d\ndy:%d\ndown:0\n\n", dx, dy)
-	return ps.writeMessage(websocket.TextMessage, []byte(msg))
+	return ps.ScrollContext(context.Background(), dx, dy)
+}
+
+// ScrollContext is like Scroll but honours ctx.
+func (ps *PointerSocket) ScrollContext(ctx context.Context, dx, dy int) (err error) {
+	return ps.writePointer(ctx, "scroll", dx, dy)
+}
+
+// writePointer sends a pointer message with the current state of the
+// pointer button.
+func (ps *PointerSocket) writePointer(ctx context.Context, ptype string, dx, dy int) error {
+	ps.Lock()
+	down := ps.down
+	ps.Unlock()
+	return ps.writePointerButton(ctx, ptype, dx, dy, down)
+}
+
+func (ps *PointerSocket) writePointerButton(ctx context.Context, ptype string, dx, dy int, down bool) error {
+	var downInt int
+	if down {
+		downInt = 1
+	}
+	msg := fmt.Sprintf("type:%s\ndx:%d\ndy:%d\ndown:%d\n\n", ptype, dx, dy, downInt)
+	return ps.write(ctx, websocket.TextMessage, []byte(msg))
 }
 
 func (ps *PointerSocket) Click() (err error) {
+	return ps.ClickContext(context.Background())
+}
+
+// ClickContext is like Click but honours ctx.
+func (ps *PointerSocket) ClickContext(ctx context.Context) (err error) {
 	msg := "type: click\n\n"
-	return ps.writeMessage(websocket.TextMessage, []byte(msg))
+	return ps.write(ctx, websocket.TextMessage, []byte(msg))
 }
@@ -0,0 +1,183 @@
+package webostv_test
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPointerSocket(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	ps, err := tv.NewPointerSocket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.Close()
+
+	err = ps.Input("button", "HOME")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := <-s.PointerEvents()
+	if ev["type"] != "button" || ev["name"] != "HOME" {
+		t.Errorf("unexpected pointer event: %v", ev)
+	}
+
+	err = ps.Press(webostv.ButtonVolumeUp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev = <-s.PointerEvents()
+	if ev["type"] != "button" || ev["name"] != "VOLUMEUP" {
+		t.Errorf("unexpected pointer event: %v", ev)
+	}
+
+	err = ps.Press(webostv.Button("VOLUMUP"))
+	if errors.Cause(err) != webostv.ErrInvalidButton {
+		t.Errorf("expected ErrInvalidButton, got %v", err)
+	}
+
+	err = ps.Move(3, -4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev = <-s.PointerEvents()
+	if ev["type"] != "move" || ev["dx"] != "3" || ev["dy"] != "-4" {
+		t.Errorf("unexpected pointer event: %v", ev)
+	}
+}
+
+func TestPointerSocketDialer(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	var dials int32
+	dialer := s.Dialer()
+	netDial := dialer.WebsocketDialer.NetDialContext
+	dialer.WebsocketDialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		atomic.AddInt32(&dials, 1)
+		return netDial(ctx, network, addr)
+	}
+	tv, err := dialer.Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tv.Close()
+	go tv.MessageHandler()
+	_, err = tv.Register("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ps, err := tv.NewPointerSocket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps.Close()
+	err = tv.Pointer().Press(webostv.ButtonHome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&dials); n != 3 {
+		t.Errorf("Dialer was used %d times, expected 3", n)
+	}
+}
+
+func TestPointerSocketConnectUnlocked(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	block := make(chan struct{})
+	s.Handle(webostv.UriGetPointerInputSocket, func(webostv.Payload) (webostv.Payload, error) {
+		<-block
+		return nil, errors.New("500 blocked")
+	})
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+	ps := tv.Pointer()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	err := ps.PressContext(ctx, webostv.ButtonHome)
+	if errors.Cause(err) != webostv.ErrTimeout {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+
+	// Close must not wait for the connection attempt of Press
+	pressed := make(chan error, 1)
+	go func() {
+		pressed <- ps.Press(webostv.ButtonHome)
+	}()
+	time.Sleep(time.Millisecond * 50)
+	closed := make(chan struct{})
+	go func() {
+		ps.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second * 2):
+		t.Fatal("Close blocked by the connection attempt")
+	}
+	close(block)
+	if err = <-pressed; err == nil {
+		t.Error("expected error from Press on a closed socket")
+	}
+}
+
+func TestManagedPointerSocket(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	tv := registerTestTv(t, s)
+	defer tv.Close()
+
+	ps := tv.Pointer()
+	if tv.Pointer() != ps {
+		t.Error("Pointer returned a different socket")
+	}
+	err := ps.Press(webostv.ButtonHome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-s.PointerEvents(); ev["name"] != "HOME" {
+		t.Errorf("unexpected pointer event: %v", ev)
+	}
+
+	s.DropPointerConnections()
+
+	// writes to the lost connection may vanish, but none must fail
+	deadline := time.After(time.Second * 5)
+	for received := false; !received; {
+		err = ps.Press(webostv.ButtonBack)
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case ev := <-s.PointerEvents():
+			if ev["name"] != "BACK" {
+				t.Errorf("unexpected pointer event: %v", ev)
+			}
+			received = true
+		case <-time.After(time.Millisecond * 10):
+		case <-deadline:
+			t.Fatal("pointer socket was not reconnected")
+		}
+	}
+
+	tv.Close()
+	err = ps.Press(webostv.ButtonHome)
+	if err != webostv.ErrPointerSocketClosed {
+		t.Errorf("expected ErrPointerSocketClosed, got %v", err)
+	}
+}
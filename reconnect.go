@@ -0,0 +1,171 @@
+package webostv
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"time"
+)
+
+var (
+	ReconnectMinDelay = time.Second
+	ReconnectMaxDelay = time.Minute
+)
+
+var errTvClosed = errors.New("tv closed")
+
+// SupervisedMessageHandler can be used instead of MessageHandler. Instead of
+// returning when the connection to the TV fails, it redials the TV with
+// exponential backoff, registers again with the client key and options
+// of the previous successful registration and re-establishes all active
+// subscriptions. Requests which are in flight when the connection fails
+// return ErrNoResponse, but subscriptions and thus functions such as
+// AudioMonitorStatus keep running.
+//
+// SupervisedMessageHandler returns when ctx is done or when the Tv is
+// closed. The connection to the TV is closed when ctx is done.
+func (tv *Tv) SupervisedMessageHandler(ctx context.Context) (err error) {
+	defer tv.closeRespChs()
+
+	ws := tv.conn()
+	resume := false
+	minDelay := ReconnectMinDelay
+	delay := minDelay
+	for {
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- tv.readMessages(ws)
+		}()
+		if resume {
+			err = tv.resume(ctx)
+			if err != nil {
+				tv.logWarn("resuming session failed", "error", err)
+				ws.Close()
+			} else {
+				delay = minDelay
+			}
+			tv.hookReconnect(err)
+		}
+
+		select {
+		case err = <-errCh:
+		case <-ctx.Done():
+			tv.Close()
+			<-errCh
+			return ctx.Err()
+		}
+		tv.failPendingRequests()
+
+		tv.wsMutex.Lock()
+		closed := tv.closed
+		tv.wsMutex.Unlock()
+		if closed {
+			return nil
+		}
+		tv.logWarn("connection lost", "error", err)
+		tv.hookDisconnect(err)
+
+		ws, delay, err = tv.redial(ctx, delay)
+		if err == errTvClosed {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resume = true
+	}
+	// not reached
+}
+
+// redial dials the TV until it succeeds, ctx is done or the Tv is closed.
+// It waits delay before the first attempt and backs off exponentially. The
+// returned delay is to be used for the next redial if the session can not
+// be resumed, so that a TV which rejects the registration is not retried
+// at the minimum delay.
+func (tv *Tv) redial(ctx context.Context, delay time.Duration) (ws *websocket.Conn, next time.Duration, err error) {
+	for {
+		select {
+		case <-time.After(delay):
+		case <-tv.done:
+			return nil, delay, errTvClosed
+		case <-ctx.Done():
+			return nil, delay, ctx.Err()
+		}
+		delay *= 2
+		if delay > ReconnectMaxDelay {
+			delay = ReconnectMaxDelay
+		}
+
+		tv.logDebug("reconnecting")
+		expected := tv.Fingerprint()
+		if expected == "" {
+			expected = tv.dialer.pinnedFingerprint(tv.Address)
+		}
+		var fingerprint string
+		ws, fingerprint, err = tv.dialer.dialTv(ctx, tv.Address, expected)
+		if IsCertificateMismatch(err) {
+			return nil, delay, err
+		}
+		if err == nil {
+			tv.wsMutex.Lock()
+			closed := tv.closed
+			if !closed {
+				tv.ws = ws
+				tv.fingerprint = fingerprint
+			}
+			tv.wsMutex.Unlock()
+			if closed {
+				ws.Close()
+				return nil, delay, errTvClosed
+			}
+			return ws, delay, nil
+		}
+		tv.logWarn("reconnect failed", "error", err)
+	}
+	// not reached
+}
+
+// resume registers again and re-establishes the subscriptions after a
+// reconnect.
+func (tv *Tv) resume(ctx context.Context) (err error) {
+	tv.wsMutex.Lock()
+	opts := tv.registerOpts
+	tv.wsMutex.Unlock()
+
+	_, err = tv.RegisterWithOptions(ctx, opts)
+	if err != nil {
+		return errors.Wrap(err, "register failed")
+	}
+
+	tv.respChMutex.Lock()
+	msgs := make([]Msg, 0, len(tv.subs))
+	for id, sub := range tv.subs {
+		msgs = append(msgs, Msg{
+			Type:    "subscribe",
+			Id:      id,
+			Uri:     sub.uri,
+			Payload: sub.req,
+		})
+	}
+	tv.respChMutex.Unlock()
+
+	for i := range msgs {
+		err = tv.writeJSON(ctx, &msgs[i])
+		if err != nil {
+			return errors.Wrap(err, "resubscribe failed")
+		}
+	}
+	return nil
+}
+
+// failPendingRequests closes the response channels of requests which
+// will never receive a response because the connection was lost.
+// Subscriptions are kept.
+func (tv *Tv) failPendingRequests() {
+	tv.respChMutex.Lock()
+	for id, ch := range tv.respCh {
+		close(ch)
+		delete(tv.respCh, id)
+	}
+	tv.respChMutex.Unlock()
+}
@@ -0,0 +1,126 @@
+package webostv_test
+
+import (
+	"context"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSupervisedMessageHandler(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse("ssap://audio/getStatus", webostv.Payload{"volume": 1})
+
+	defer func(d time.Duration) { webostv.ReconnectMinDelay = d }(webostv.ReconnectMinDelay)
+	webostv.ReconnectMinDelay = time.Millisecond * 10
+
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tv.SupervisedMessageHandler(ctx)
+
+	_, err = tv.Register("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volumes := make(chan int)
+	go tv.AudioMonitorStatusContext(ctx, func(as webostv.AudioStatus) error {
+		volumes <- as.Volume
+		return nil
+	})
+	if v := <-volumes; v != 1 {
+		t.Errorf("got volume %d, expected 1", v)
+	}
+
+	s.DropConnections()
+
+	// the subscription is re-established and the initial value is sent again
+	select {
+	case v := <-volumes:
+		if v != 1 {
+			t.Errorf("got volume %d, expected 1", v)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("subscription was not resumed")
+	}
+	s.Publish("ssap://audio/getStatus", webostv.Payload{"volume": 7})
+	if v := <-volumes; v != 7 {
+		t.Errorf("got volume %d, expected 7", v)
+	}
+}
+
+func TestSupervisedMessageHandlerClose(t *testing.T) {
+	s := webostvtest.NewServer()
+
+	defer func(d time.Duration) { webostv.ReconnectMinDelay = d }(webostv.ReconnectMinDelay)
+	webostv.ReconnectMinDelay = time.Millisecond * 10
+
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- tv.SupervisedMessageHandler(context.Background())
+	}()
+	_, err = tv.Register("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the TV becomes unreachable and the Tv is closed during the outage
+	s.Close()
+	time.Sleep(time.Millisecond * 50)
+	tv.Close()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("SupervisedMessageHandler did not return after Close")
+	}
+}
+
+func TestSupervisedMessageHandlerBackoff(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	defer func(d time.Duration) { webostv.ReconnectMinDelay = d }(webostv.ReconnectMinDelay)
+	webostv.ReconnectMinDelay = time.Millisecond * 10
+
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tv.Close()
+	var reconnects int32
+	tv.SetHooks(webostv.Hooks{
+		Reconnect: func(error) { atomic.AddInt32(&reconnects, 1) },
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tv.SupervisedMessageHandler(ctx)
+	_, err = tv.Register("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the TV rejects the registration when the session is resumed
+	s.RevokeClientKeys()
+	s.PairingFunc = func() bool { return false }
+	s.DropConnections()
+	time.Sleep(time.Millisecond * 300)
+
+	// 10+20+40+80+160 ms with backoff, about 30 attempts without
+	if n := atomic.LoadInt32(&reconnects); n == 0 || n > 8 {
+		t.Errorf("%d reconnects in 300 ms, expected backoff", n)
+	}
+}
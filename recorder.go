@@ -0,0 +1,146 @@
+package webostv
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// Directions of a Frame.
+const (
+	FrameSent     = "send"
+	FrameReceived = "recv"
+)
+
+// Websocket message types of a Frame.
+const (
+	FrameText   = "text"
+	FrameBinary = "binary"
+)
+
+// SocketPointer is the Socket of the frames of the pointer input socket.
+const SocketPointer = "pointer"
+
+// Frame is a websocket message exchanged with the TV, as recorded by a
+// Recorder.
+type Frame struct {
+	Time      time.Time       `json:"time"`
+	Direction string          `json:"dir"`                 // FrameSent or FrameReceived
+	Socket    string          `json:"socket,omitempty"`    // SocketPointer, empty for the ssap socket
+	FrameType string          `json:"frameType,omitempty"` // FrameText or FrameBinary, empty means text
+	Type      string          `json:"type,omitempty"`
+	Id        string          `json:"id,omitempty"`
+	Uri       string          `json:"uri,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`   // the message as JSON
+	Text      string          `json:"text,omitempty"`   // the text message if it is not valid JSON
+	Binary    []byte          `json:"binary,omitempty"` // the binary message
+}
+
+// Msg decodes the message of the frame.
+func (f *Frame) Msg() (msg Msg, err error) {
+	if f.Data == nil {
+		return msg, errors.New("frame does not contain a JSON message")
+	}
+	err = json.Unmarshal(f.Data, &msg)
+	return msg, err
+}
+
+// Recorder writes the messages exchanged with the TV to a capture file,
+// one JSON encoded Frame per line. Client keys are replaced with
+// "REDACTED" so that captures can be shared in bug reports. See
+// Tv.SetRecorder and webostvtest.NewReplayServer.
+type Recorder struct {
+	sync.Mutex
+	w   io.Writer
+	err error
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+// Err returns the first error which occurred while writing the capture.
+func (r *Recorder) Err() error {
+	r.Lock()
+	defer r.Unlock()
+	return r.err
+}
+
+// Record writes a text frame of the ssap socket with the given direction
+// and message data.
+func (r *Recorder) Record(dir string, data []byte) {
+	r.RecordMessage(dir, "", websocket.TextMessage, data)
+}
+
+// RecordMessage writes a frame of the given socket with the given
+// direction, websocket message type and message data.
+func (r *Recorder) RecordMessage(dir, socket string, messageType int, data []byte) {
+	f := Frame{
+		Time:      time.Now(),
+		Direction: dir,
+		Socket:    socket,
+		FrameType: FrameText,
+	}
+	var msg Msg
+	if messageType != websocket.TextMessage {
+		f.FrameType = FrameBinary
+		f.Binary = data
+	} else if json.Unmarshal(data, &msg) == nil {
+		f.Type = msg.Type
+		f.Id = msg.Id
+		f.Uri = msg.Uri
+		if _, ok := msg.Payload["client-key"]; ok {
+			msg.Payload = redactClientKey(msg.Payload)
+			data, _ = json.Marshal(msg)
+		}
+		f.Data = data
+	} else {
+		f.Text = string(data)
+	}
+	buf, err := json.Marshal(f)
+	if err != nil {
+		return
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if r.err != nil {
+		return
+	}
+	_, r.err = r.w.Write(append(buf, '\n'))
+}
+
+// ReadFrames reads a capture written by a Recorder.
+func ReadFrames(rd io.Reader) (frames []Frame, err error) {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var f Frame
+		err = json.Unmarshal(scanner.Bytes(), &f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "capture line %d", line)
+		}
+		frames = append(frames, f)
+	}
+	return frames, scanner.Err()
+}
+
+// SetRecorder sets a Recorder which records all messages exchanged with
+// the TV, including those of the pointer sockets. It should be called
+// before the message handler is started.
+func (tv *Tv) SetRecorder(r *Recorder) {
+	tv.recorder = r
+}
+
+func (tv *Tv) record(dir string, messageType int, data []byte) {
+	if tv.recorder != nil {
+		tv.recorder.RecordMessage(dir, "", messageType, data)
+	}
+}
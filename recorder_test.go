@@ -0,0 +1,156 @@
+package webostv_test
+
+import (
+	"bytes"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	volume := int32(9)
+	s.Handle("ssap://audio/getStatus", func(webostv.Payload) (webostv.Payload, error) {
+		return webostv.Payload{"volume": atomic.LoadInt32(&volume)}, nil
+	})
+
+	var capture bytes.Buffer
+	rec := webostv.NewRecorder(&capture)
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tv.SetRecorder(rec)
+	go tv.MessageHandler()
+	key, err := tv.Register("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tv.AudioGetStatus()
+	atomic.StoreInt32(&volume, 10)
+	tv.AudioGetStatus()
+	_, err = tv.Request("ssap://tv/getCurrentChannel", nil)
+	if err == nil {
+		t.Fatal("expected error from unhandled URI")
+	}
+	ch := make(chan webostv.Msg, 10)
+	_, err = tv.Subscribe("ssap://audio/getStatus", nil, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	s.Publish("ssap://audio/getStatus", webostv.Payload{"volume": 11})
+	<-ch
+	tv.Close()
+	if rec.Err() != nil {
+		t.Fatal(rec.Err())
+	}
+	if strings.Contains(capture.String(), key) {
+		t.Error("client key not redacted from capture")
+	}
+
+	frames, err := webostv.ReadFrames(&capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 12 {
+		t.Fatalf("captured %d frames, expected 12", len(frames))
+	}
+	if f := frames[3]; f.Direction != webostv.FrameSent || f.Type != "request" || f.Uri != "ssap://audio/getStatus" {
+		t.Errorf("unexpected frame: %+v", f)
+	}
+
+	rs := webostvtest.NewReplayServer(frames)
+	defer rs.Close()
+	tv, err = rs.Dialer().Dial(rs.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tv.Close()
+	go tv.MessageHandler()
+
+	key, err = tv.Register("")
+	if err != nil || key != "REDACTED" {
+		t.Fatalf("unexpected registration result %q, %v", key, err)
+	}
+	for _, expected := range []int{9, 10, 10} {
+		as, err := tv.AudioGetStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if as.Volume != expected {
+			t.Errorf("replayed volume %d, expected %d", as.Volume, expected)
+		}
+	}
+	_, err = tv.Request("ssap://tv/getCurrentChannel", nil)
+	if apiErr, ok := errors.Cause(err).(*webostv.APIError); !ok || apiErr.StatusCode() != 404 {
+		t.Errorf("unexpected replayed error: %v", err)
+	}
+	ch = make(chan webostv.Msg, 10)
+	_, err = tv.Subscribe("ssap://audio/getStatus", nil, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []float64{10, 11} {
+		msg := <-ch
+		if msg.Payload["volume"] != expected {
+			t.Errorf("unexpected replayed subscription message: %+v", msg)
+		}
+	}
+	_, err = tv.Request("ssap://system/getSystemInfo", nil)
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("unexpected error for unrecorded request: %v", err)
+	}
+}
+
+func TestRecordFrames(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	var capture bytes.Buffer
+	rec := webostv.NewRecorder(&capture)
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tv.SetRecorder(rec)
+	go tv.MessageHandler()
+	_, err = tv.Register("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tv.Pointer().Press(webostv.ButtonHome)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-s.PointerEvents()
+	tv.Close()
+	rec.RecordMessage(webostv.FrameReceived, "", websocket.BinaryMessage, []byte{0, 1, 0xff})
+
+	frames, err := webostv.ReadFrames(&capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pointer bool
+	for _, f := range frames[:len(frames)-1] {
+		if f.FrameType != webostv.FrameText {
+			t.Errorf("unexpected frame type: %+v", f)
+		}
+		if f.Socket == webostv.SocketPointer && f.Direction == webostv.FrameSent &&
+			f.Text == "type:button\nname:HOME\n\n" {
+			pointer = true
+		}
+	}
+	if !pointer {
+		t.Error("pointer socket frame not recorded")
+	}
+	f := frames[len(frames)-1]
+	if f.FrameType != webostv.FrameBinary || !bytes.Equal(f.Binary, []byte{0, 1, 0xff}) || f.Data != nil {
+		t.Errorf("unexpected binary frame: %+v", f)
+	}
+}
@@ -0,0 +1,394 @@
+package webostv
+
+import (
+	"context"
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"net"
+)
+
+// DefaultPermissions are the permissions requested in the unsigned part of
+// the manifest if RegisterOptions.Permissions is nil.
+var DefaultPermissions = []string{
+	"LAUNCH",
+	"LAUNCH_WEBAPP",
+	"APP_TO_APP",
+	"CLOSE",
+	"TEST_OPEN",
+	"TEST_PROTECTED",
+	"CONTROL_AUDIO",
+	"CONTROL_DISPLAY",
+	"CONTROL_INPUT_JOYSTICK",
+	"CONTROL_INPUT_MEDIA_RECORDING",
+	"CONTROL_INPUT_MEDIA_PLAYBACK",
+	"CONTROL_INPUT_TV",
+	"CONTROL_POWER",
+	"READ_APP_STATUS",
+	"READ_CURRENT_CHANNEL",
+	"READ_INPUT_DEVICE_LIST",
+	"READ_NETWORK_STATE",
+	"READ_RUNNING_APPS",
+	"READ_TV_CHANNEL_LIST",
+	"WRITE_NOTIFICATION_TOAST",
+	"READ_POWER_STATE",
+	"READ_COUNTRY_INFO",
+}
+
+// SignedManifest is the signed part of the pairing manifest together with
+// its signatures.
+type SignedManifest struct {
+	Signed     map[string]interface{}
+	Signatures []map[string]interface{}
+}
+
+// DefaultSignedManifest returns the signed manifest of the LG test
+// application which is used if RegisterOptions.SignedManifest is nil.
+func DefaultSignedManifest() *SignedManifest {
+	return &SignedManifest{
+		Signed: map[string]interface{}{
+			"created":  "20140509",
+			"appId":    "com.lge.test",
+			"vendorId": "com.lge",
+			"localizedAppNames": map[string]string{
+				"":       "LG Remote App",
+				"ko-KR":  "리모컨 앱",
+				"zxx-XX": "ЛГ Rэмotэ AПП",
+			},
+			"localizedVendorNames": map[string]string{
+				"": "LG Electronics",
+			},
+			"permissions": []string{
+				"TEST_SECURE",
+				"CONTROL_INPUT_TEXT",
+				"CONTROL_MOUSE_AND_KEYBOARD",
+				"READ_INSTALLED_APPS",
+				"READ_LGE_SDX",
+				"READ_NOTIFICATIONS",
+				"SEARCH",
+				"WRITE_SETTINGS",
+				"WRITE_NOTIFICATION_ALERT",
+				"CONTROL_POWER",
+				"READ_CURRENT_CHANNEL",
+				"READ_RUNNING_APPS",
+				"READ_UPDATE_INFO",
+				"UPDATE_FROM_REMOTE_APP",
+				"READ_LGE_TV_INPUT_EVENTS",
+				"READ_TV_CURRENT_TIME",
+			},
+			"serial": "2f930e2d2cfe083771f68e4fe7bb07",
+		},
+		Signatures: []map[string]interface{}{
+			map[string]interface{}{
+				"signatureVersion": 1,
+				"signature":        "eyJhbGdvcml0aG0iOiJSU0EtU0hBMjU2Iiwia2V5SWQiOiJ0ZXN0LXNpZ25pbmctY2VydCIsInNpZ25hdHVyZVZlcnNpb24iOjF9.hrVRgjCwXVvE2OOSpDZ58hR+59aFNwYDyjQgKk3auukd7pcegmE2CzPCa0bJ0ZsRAcKkCTJrWo5iDzNhMBWRyaMOv5zWSrthlf7G128qvIlpMT0YNY+n/FaOHE73uLrS/g7swl3/qH/BGFG2Hu4RlL48eb3lLKqTt2xKHdCs6Cd4RMfJPYnzgvI4BNrFUKsjkcu+WD4OO2A27Pq1n50cMchmcaXadJhGrOqH5YmHdOCj5NSHzJYrsW0HPlpuAx/ECMeIZYDh6RMqaFM2DXzdKX9NmmyqzJ3o/0lkk/N97gfVRLW5hA29yeAwaCViZNCP8iC9aO0q9fQojoa7NQnAtw==",
+			},
+		},
+	}
+}
+
+const (
+	PairingTypePrompt = "PROMPT"
+	PairingTypePin    = "PIN"
+)
+
+// RegisterOptions control the pairing manifest sent by RegisterWithOptions.
+// The zero value produces the same manifest as Register.
+type RegisterOptions struct {
+	ClientKey    string // client key from a previous registration, if any
+	ForcePairing bool
+
+	// PairingType is PairingTypePrompt (default) or PairingTypePin.
+	PairingType string
+	// PinFunc is called when the TV displays a PIN and requests it to
+	// be entered. The returned PIN is sent to the TV with
+	// PairingSetPin. PinFunc is required with PairingTypePin.
+	PinFunc func(ctx context.Context) (pin string, err error)
+
+	// Permissions are requested in the unsigned part of the manifest.
+	// If nil, DefaultPermissions are requested.
+	//
+	// The permissions of the signed part of the manifest are requested
+	// as well and can not be reduced without invalidating the signature.
+	// The default signed manifest requests CONTROL_POWER,
+	// CONTROL_INPUT_TEXT, READ_INSTALLED_APPS and more, so Permissions
+	// alone does not limit the access of the client. Therefore
+	// Permissions requires a SignedManifest with the signed permissions
+	// reduced accordingly, which TVs that verify the signature reject.
+	Permissions []string
+
+	// SignedManifest is the signed part of the manifest, including the
+	// app and vendor names shown in the pairing prompt. If nil,
+	// DefaultSignedManifest is used. Any change to the signed part
+	// invalidates the signatures of the default manifest.
+	SignedManifest *SignedManifest
+
+	// DetectCapabilities makes the registration call
+	// Tv.DetectCapabilities, so that the wrapper methods fail fast with
+	// ErrUnsupported if the TV does not have the endpoint.
+	DetectCapabilities bool
+}
+
+type RegisterResult struct {
+	ClientKey string
+	// Permissions granted by the TV, if the TV reports them. TVs
+	// usually do not, in which case Permissions is empty and the
+	// granted permissions are not known; requests which need a
+	// permission which was not granted fail with "401 insufficient
+	// permissions".
+	Permissions []string
+	// Fingerprint of the TLS certificate of the TV, see Tv.Fingerprint.
+	// It should be stored together with the client key and pinned with
+	// Dialer.PinnedFingerprint.
+	Fingerprint string
+	// UUID of the TV. It is only set if Dialer.KeyStore is used.
+	UUID string
+}
+
+func helloPayload(opts RegisterOptions) Payload {
+	sm := opts.SignedManifest
+	if sm == nil {
+		sm = DefaultSignedManifest()
+	}
+	permissions := opts.Permissions
+	if permissions == nil {
+		permissions = DefaultPermissions
+	}
+	pairingType := opts.PairingType
+	if pairingType == "" {
+		pairingType = PairingTypePrompt
+	}
+
+	p := Payload{
+		"forcePairing": opts.ForcePairing,
+		"pairingType":  pairingType,
+		"manifest": map[string]interface{}{
+			"manifestVersion": 1,
+			"appVersion":      "1.1",
+			"signed":          sm.Signed,
+			"permissions":     permissions,
+			"signatures":      sm.Signatures,
+		},
+	}
+	if opts.ClientKey != "" {
+		p["client-key"] = opts.ClientKey
+	}
+	return p
+}
+
+func (tv *Tv) Register(key string) (newKey string, err error) {
+	return tv.RegisterContext(context.Background(), key)
+}
+
+// RegisterContext is like Register but honours ctx. RegisterTimeout is
+// applied if ctx has no deadline.
+func (tv *Tv) RegisterContext(ctx context.Context, key string) (newKey string, err error) {
+	result, err := tv.RegisterWithOptions(ctx, RegisterOptions{ClientKey: key})
+	return result.ClientKey, err
+}
+
+// RegisterWithOptions registers with a manifest built from opts.
+// RegisterTimeout is applied if ctx has no deadline.
+func (tv *Tv) RegisterWithOptions(ctx context.Context, opts RegisterOptions) (result RegisterResult, err error) {
+	ctx, cancel := withDefaultTimeout(ctx, RegisterTimeout)
+	defer cancel()
+
+	if opts.PairingType == PairingTypePin && opts.PinFunc == nil {
+		return result, errors.New("PinFunc is required for PIN pairing")
+	}
+	if opts.Permissions != nil && opts.SignedManifest == nil {
+		return result, errors.New("Permissions require a SignedManifest, the signed permissions of the default manifest can not be reduced")
+	}
+
+	ks := tv.auxDialer().KeyStore
+	var stored Credentials
+	if ks != nil {
+		result.UUID, stored, err = tv.loadCredentials(ctx)
+		if err != nil {
+			return result, err
+		}
+		if opts.ClientKey == "" {
+			opts.ClientKey = stored.ClientKey
+		}
+	}
+
+	helloMsg := Msg{
+		Type:    "register",
+		Id:      makeId(),
+		Payload: helloPayload(opts),
+	}
+	// room for the pairing responses and the final registered message
+	ch := make(chan Msg, 4)
+	tv.registerRespCh(helloMsg.Id, ch)
+	defer tv.unregisterRespCh(helloMsg.Id)
+
+	err = tv.writeJSON(ctx, &helloMsg)
+	if err != nil {
+		return result, err
+	}
+
+	var respMsg Msg
+	var ok bool
+
+	for {
+		select {
+		case respMsg, ok = <-ch:
+			if !ok {
+				return result, ErrNoResponse
+			}
+			err = checkResponse("", respMsg)
+			if err != nil {
+				return result, err
+			}
+			if respMsg.Type == "response" && respMsg.Payload["pairingType"] != nil {
+				// the prompt belongs to the register request, so
+				// the message handler does not emit it
+				tv.emitEvent(respMsg)
+			}
+			if respMsg.Type == "response" && respMsg.Payload["pairingType"] == PairingTypePin {
+				err = tv.enterPin(ctx, opts)
+				if err != nil {
+					return result, err
+				}
+			}
+
+		case <-ctx.Done():
+			return result, contextError(ctx)
+		}
+		if respMsg.Type != "response" {
+			break
+		}
+	}
+	if respMsg.Type != "registered" {
+		return result, ErrRegistrationFailed
+	}
+	if tmp, ok := respMsg.Payload["client-key"]; ok {
+		tmp, ok := tmp.(string)
+		if !ok {
+			return result, errors.New("client-key from TV is not a string")
+		}
+		result.ClientKey = tmp
+	}
+	if tmp, ok := respMsg.Payload["permissions"].([]interface{}); ok {
+		for _, perm := range tmp {
+			if perm, ok := perm.(string); ok {
+				result.Permissions = append(result.Permissions, perm)
+			}
+		}
+	}
+
+	if result.ClientKey != "" {
+		opts.ClientKey = result.ClientKey
+	}
+	opts.ForcePairing = false
+	tv.wsMutex.Lock()
+	tv.registerOpts = opts
+	result.Fingerprint = tv.fingerprint
+	tv.wsMutex.Unlock()
+
+	if ks != nil {
+		cred := Credentials{
+			ClientKey:   opts.ClientKey,
+			Fingerprint: result.Fingerprint,
+			Address:     tv.Address,
+			MAC:         stored.MAC,
+		}
+		if cred.MAC == "" {
+			cred.MAC = tv.macAddress(ctx)
+		}
+		if cred != stored {
+			err = ks.Save(result.UUID, cred)
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+	if opts.DetectCapabilities {
+		_, err = tv.DetectCapabilities(ctx)
+		if err != nil {
+			tv.logWarn("detecting capabilities failed", "error", err)
+		}
+	}
+	return result, nil
+}
+
+// macAddress asks the MAC address of the TV for Wake-on-LAN. An empty
+// string is returned if the TV does not tell it.
+func (tv *Tv) macAddress(ctx context.Context) string {
+	info, err := tv.GetCurrentSWInformationContext(ctx)
+	if err != nil {
+		tv.logDebug("getting MAC address failed", "error", err)
+		return ""
+	}
+	if _, err = net.ParseMAC(info.DeviceId); err != nil {
+		return ""
+	}
+	return info.DeviceId
+}
+
+// loadCredentials asks the UUID of the TV and loads its credentials from
+// the KeyStore. The certificate of the TV is verified against the stored
+// fingerprint before the client key is used.
+func (tv *Tv) loadCredentials(ctx context.Context) (uuid string, cred Credentials, err error) {
+	info, err := tv.Hello(ctx)
+	if err != nil {
+		return "", cred, err
+	}
+	if info.DeviceUUID == "" {
+		return "", cred, errors.New("TV did not report its UUID")
+	}
+	cred, err = tv.auxDialer().KeyStore.Load(info.DeviceUUID)
+	if err != nil {
+		return "", cred, err
+	}
+	err = checkFingerprint(tv.Address, cred.Fingerprint, tv.Fingerprint())
+	if err != nil {
+		return "", cred, err
+	}
+	return info.DeviceUUID, cred, nil
+}
+
+// Hello sends a "hello" message. The TV responds with information about
+// itself, including its UUID.
+func (tv *Tv) Hello(ctx context.Context) (info HelloInfo, err error) {
+	ctx, cancel := withDefaultTimeout(ctx, Timeout)
+	defer cancel()
+
+	msg := Msg{
+		Type:    "hello",
+		Id:      makeId(),
+		Payload: Payload{},
+	}
+	ch := make(chan Msg, 1)
+	tv.registerRespCh(msg.Id, ch)
+	defer tv.unregisterRespCh(msg.Id)
+
+	err = tv.writeJSON(ctx, &msg)
+	if err != nil {
+		return info, err
+	}
+	select {
+	case respMsg, ok := <-ch:
+		if !ok {
+			return info, ErrNoResponse
+		}
+		if respMsg.Type != "hello" {
+			return info, checkResponse("", respMsg)
+		}
+		err = mapstructure.Decode(respMsg.Payload, &info)
+		return info, err
+	case <-ctx.Done():
+		return info, contextError(ctx)
+	}
+}
+
+// enterPin asks for the PIN displayed by the TV and sends it.
+func (tv *Tv) enterPin(ctx context.Context, opts RegisterOptions) (err error) {
+	if opts.PinFunc == nil {
+		return errors.New("TV requested a PIN but PinFunc is not set")
+	}
+	pin, err := opts.PinFunc(ctx)
+	if err != nil {
+		return errors.Wrap(err, "PIN entry failed")
+	}
+	return tv.PairingSetPinContext(ctx, pin)
+}
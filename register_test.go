@@ -0,0 +1,158 @@
+package webostv_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	prompted := 0
+	s.PairingFunc = func() bool {
+		prompted++
+		return true
+	}
+
+	tv := dialTestTv(t, s)
+	key, err := tv.Register("")
+	tv.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key == "" {
+		t.Fatal("no client key")
+	}
+	if prompted != 1 {
+		t.Errorf("prompted %d times, expected 1", prompted)
+	}
+
+	tv = dialTestTv(t, s)
+	defer tv.Close()
+	key2, err := tv.Register(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key2 != key {
+		t.Errorf("got key %q, expected %q", key2, key)
+	}
+	if prompted != 1 {
+		t.Errorf("prompted again with known client key")
+	}
+}
+
+func TestRegisterRejected(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.PairingFunc = func() bool { return false }
+
+	tv := dialTestTv(t, s)
+	defer tv.Close()
+	_, err := tv.Register("")
+	if !webostv.IsPairingRejected(err) {
+		t.Fatalf("expected pairing rejected error, got %v", err)
+	}
+}
+
+func TestRegisterWithOptions(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tv.Close()
+	var capture bytes.Buffer
+	tv.SetRecorder(webostv.NewRecorder(&capture))
+	go tv.MessageHandler()
+
+	opts := webostv.RegisterOptions{
+		Permissions: []string{"CONTROL_AUDIO"},
+	}
+	_, err = tv.RegisterWithOptions(context.Background(), opts)
+	if err == nil {
+		t.Fatal("Permissions accepted with the default signed manifest")
+	}
+
+	opts.SignedManifest = webostv.DefaultSignedManifest()
+	opts.SignedManifest.Signed["permissions"] = []string{"READ_CURRENT_CHANNEL"}
+	result, err := tv.RegisterWithOptions(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ClientKey == "" {
+		t.Fatal("no client key")
+	}
+	if result.Permissions != nil {
+		// the TV did not report the granted permissions
+		t.Errorf("unexpected permissions: %v", result.Permissions)
+	}
+
+	frames, err := webostv.ReadFrames(&capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest struct {
+		Signed struct {
+			Permissions []string
+		}
+		Permissions []string
+	}
+	for _, f := range frames {
+		msg, err := f.Msg()
+		if err == nil && f.Direction == webostv.FrameSent && msg.Type == "register" {
+			buf, _ := json.Marshal(msg.Payload["manifest"])
+			json.Unmarshal(buf, &manifest)
+		}
+	}
+	if fmt.Sprint(manifest.Signed.Permissions, manifest.Permissions) != "[READ_CURRENT_CHANNEL] [CONTROL_AUDIO]" {
+		t.Errorf("unexpected permissions in the manifest: %+v", manifest)
+	}
+}
+
+func TestRegisterPin(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.PairingFunc = func() bool {
+		t.Error("prompted with PIN pairing")
+		return false
+	}
+
+	tv := dialTestTv(t, s)
+	defer tv.Close()
+
+	pins := []string{"0000", s.Pin}
+	result, err := tv.RegisterWithOptions(context.Background(), webostv.RegisterOptions{
+		PairingType: webostv.PairingTypePin,
+		PinFunc: func(ctx context.Context) (string, error) {
+			pin := pins[0]
+			pins = pins[1:]
+			return pin, nil
+		},
+	})
+	if err == nil {
+		t.Fatal("registration succeeded with wrong PIN")
+	}
+
+	result, err = tv.RegisterWithOptions(context.Background(), webostv.RegisterOptions{
+		PairingType: webostv.PairingTypePin,
+		PinFunc: func(ctx context.Context) (string, error) {
+			pin := pins[0]
+			pins = pins[1:]
+			return pin, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ClientKey == "" {
+		t.Fatal("no client key")
+	}
+}
@@ -0,0 +1,154 @@
+package webostv
+
+import (
+	"sync"
+)
+
+// OverflowPolicy tells what happens when messages arrive to a subscription
+// faster than the subscriber consumes them and its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest drops the oldest queued message.
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowCoalesce keeps only the latest message in the queue. This
+	// suits status subscriptions where each message supersedes the
+	// previous one. QueueSize is ignored.
+	OverflowCoalesce
+	// OverflowBlock waits until there is room in the queue. This stops
+	// the message handler, delaying all other responses, so it should
+	// only be used with subscribers which are known to be fast.
+	OverflowBlock
+)
+
+type SubscribeOptions struct {
+	QueueSize int // maximum number of queued messages
+	Overflow  OverflowPolicy
+}
+
+// DefaultSubscribeOptions are used by Subscribe and MonitorStatus.
+var DefaultSubscribeOptions = SubscribeOptions{
+	QueueSize: 32,
+	Overflow:  OverflowDropOldest,
+}
+
+// subQueue is a bounded message queue between the message handler and a
+// subscriber channel. A goroutine forwards the queued messages to the
+// subscriber channel so that the message handler does not need to wait
+// for the subscriber.
+type subQueue struct {
+	opts   SubscribeOptions
+	out    chan<- Msg
+	notify chan struct{} // signaled when messages are added
+	space  chan struct{} // signaled when messages are removed
+	done   chan struct{} // closed when the queue is closed
+
+	sync.Mutex
+	msgs    []Msg
+	closed  bool
+	dropped int
+}
+
+func newSubQueue(out chan<- Msg, opts SubscribeOptions) *subQueue {
+	if opts.QueueSize < 1 || opts.Overflow == OverflowCoalesce {
+		opts.QueueSize = 1
+	}
+	q := &subQueue{
+		opts:   opts,
+		out:    out,
+		notify: make(chan struct{}, 1),
+		space:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	go q.forward()
+	return q
+}
+
+// push adds msg to the queue. It only blocks with OverflowBlock. It
+// returns false if a message was dropped, which is never the case with
+// OverflowCoalesce.
+func (q *subQueue) push(msg Msg) (ok bool) {
+	ok = true
+	q.Lock()
+	for len(q.msgs) >= q.opts.QueueSize && q.opts.Overflow == OverflowBlock && !q.closed {
+		q.Unlock()
+		select {
+		case <-q.space:
+		case <-q.done:
+		}
+		q.Lock()
+	}
+	if q.closed {
+		q.Unlock()
+		return true
+	}
+	if len(q.msgs) >= q.opts.QueueSize {
+		q.msgs = q.msgs[1:]
+		// replacing the pending message is not a loss with
+		// OverflowCoalesce
+		if q.opts.Overflow != OverflowCoalesce {
+			q.dropped++
+			ok = false
+		}
+	}
+	q.msgs = append(q.msgs, msg)
+	q.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return ok
+}
+
+func (q *subQueue) pop() (msg Msg, ok bool) {
+	q.Lock()
+	if len(q.msgs) > 0 {
+		msg = q.msgs[0]
+		q.msgs = q.msgs[1:]
+		ok = true
+	}
+	q.Unlock()
+	if ok {
+		select {
+		case q.space <- struct{}{}:
+		default:
+		}
+	}
+	return msg, ok
+}
+
+// forward sends the queued messages to the subscriber channel and closes
+// it when the queue is closed.
+func (q *subQueue) forward() {
+	defer close(q.out)
+	for {
+		select {
+		case <-q.notify:
+		case <-q.done:
+			return
+		}
+		for {
+			msg, ok := q.pop()
+			if !ok {
+				break
+			}
+			select {
+			case q.out <- msg:
+			case <-q.done:
+				return
+			}
+		}
+	}
+}
+
+// close stops the queue. Queued messages are discarded and the
+// subscriber channel is closed.
+func (q *subQueue) close() {
+	q.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.done)
+	}
+	q.Unlock()
+}
@@ -0,0 +1,77 @@
+package webostv_test
+
+import (
+	"context"
+	"github.com/snabb/webostv"
+	"github.com/snabb/webostv/webostvtest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSlowSubscriber(t *testing.T) {
+	s := webostvtest.NewServer()
+	defer s.Close()
+	s.HandleResponse("ssap://audio/getStatus", webostv.Payload{"volume": 0})
+	s.HandleResponse("ssap://audio/getMute", webostv.Payload{"mute": true})
+
+	tv, err := s.Dialer().Dial(s.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tv.Close()
+	var drops int32
+	tv.SetHooks(webostv.Hooks{
+		SubscriptionMessage: func(uri string, dropped bool) {
+			if dropped {
+				atomic.AddInt32(&drops, 1)
+			}
+		},
+	})
+	go tv.MessageHandler()
+	_, err = tv.Register("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nobody reads the channel
+	ch := make(chan webostv.Msg)
+	_, err = tv.SubscribeWithOptions(context.Background(), "ssap://audio/getStatus", nil, ch,
+		webostv.SubscribeOptions{Overflow: webostv.OverflowCoalesce})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for s.Subscribers("ssap://audio/getStatus") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 1; i <= 10; i++ {
+		s.Publish("ssap://audio/getStatus", webostv.Payload{"volume": i})
+	}
+
+	mute, err := tv.AudioGetMute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mute {
+		t.Error("unexpected mute response")
+	}
+
+	// intermediate messages are coalesced, at most one message which was
+	// already being delivered precedes the latest one
+	for i := 0; ; i++ {
+		select {
+		case msg := <-ch:
+			if msg.Payload["volume"] == float64(10) {
+				if n := atomic.LoadInt32(&drops); n != 0 {
+					t.Errorf("%d coalesced messages reported as dropped", n)
+				}
+				return
+			}
+			if i > 0 {
+				t.Fatalf("expected latest volume 10, got %v", msg.Payload["volume"])
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("latest subscription message not received")
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package webostvtest
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/snabb/webostv"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// ReplayServer is a fake TV which answers requests with the responses
+// found in a capture written by webostv.Recorder. It can be used for
+// reproducing problems reported by users of TV models which are not
+// available to the developer.
+//
+// Each request is matched to the next unanswered request in the capture
+// with the same type and URI, preferring one with an identical payload.
+// When all of them have been answered, the last one is answered again.
+// The recorded responses, including later subscription messages, are sent
+// back immediately with the id of the new request. Messages which were
+// not responses to any request are sent after the preceding exchange.
+// Requests which are not found in the capture get a "404 no recorded
+// response" error. The pointer input socket is not replayed.
+type ReplayServer struct {
+	// Address of the fake TV. It can be dialed with the Dialer returned
+	// by ReplayServer.Dialer.
+	Address string
+
+	httpServer *httptest.Server
+	initial    []webostv.Msg // sent to each client when it connects
+
+	sync.Mutex
+	exchanges []*exchange
+	conns     map[*websocket.Conn]bool
+}
+
+// exchange is a recorded request and the messages which followed it.
+type exchange struct {
+	req       webostv.Msg
+	payload   string // req.Payload as JSON, for matching
+	responses []webostv.Msg
+	used      bool
+}
+
+// NewReplayServer starts a fake TV which replays frames, as read with
+// webostv.ReadFrames. The caller should call Close when finished.
+func NewReplayServer(frames []webostv.Frame) *ReplayServer {
+	s := &ReplayServer{
+		conns: make(map[*websocket.Conn]bool),
+	}
+	byId := make(map[string]*exchange)
+	var last *exchange
+	for _, f := range frames {
+		msg, err := f.Msg()
+		if err != nil {
+			continue
+		}
+		switch f.Direction {
+		case webostv.FrameSent:
+			last = &exchange{
+				req:     msg,
+				payload: payloadKey(msg.Payload),
+			}
+			s.exchanges = append(s.exchanges, last)
+			if msg.Id != "" {
+				byId[msg.Id] = last
+			}
+		case webostv.FrameReceived:
+			if ex := byId[msg.Id]; ex != nil && msg.Id != "" {
+				ex.responses = append(ex.responses, msg)
+			} else if last != nil {
+				msg.Id = ""
+				last.responses = append(last.responses, msg)
+			} else {
+				s.initial = append(s.initial, msg)
+			}
+		}
+	}
+
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.serveSsap))
+	s.Address = s.httpServer.Listener.Addr().String()
+	return s
+}
+
+// Dialer returns a Dialer which connects to the ReplayServer regardless
+// of the address which is dialed.
+func (s *ReplayServer) Dialer() *webostv.Dialer {
+	return dialerFor(s.Address, false)
+}
+
+// Close disconnects all clients and stops the ReplayServer.
+func (s *ReplayServer) Close() {
+	s.Lock()
+	for ws := range s.conns {
+		ws.Close()
+	}
+	s.Unlock()
+	s.httpServer.Close()
+}
+
+func payloadKey(p webostv.Payload) string {
+	buf, _ := json.Marshal(p)
+	return string(buf)
+}
+
+// match returns the recorded exchange for req, or nil.
+func (s *ReplayServer) match(req webostv.Msg) *exchange {
+	s.Lock()
+	defer s.Unlock()
+
+	key := payloadKey(req.Payload)
+	var unused, repeat *exchange
+	for _, ex := range s.exchanges {
+		if ex.req.Type != req.Type || ex.req.Uri != req.Uri {
+			continue
+		}
+		if !ex.used {
+			if ex.payload == key {
+				unused = ex
+				break
+			}
+			if unused == nil {
+				unused = ex
+			}
+		} else if repeat == nil || ex.payload == key {
+			repeat = ex
+		}
+	}
+	if unused != nil {
+		unused.used = true
+		return unused
+	}
+	return repeat
+}
+
+func (s *ReplayServer) serveSsap(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &conn{ws: ws}
+	s.Lock()
+	s.conns[ws] = true
+	s.Unlock()
+
+	defer func() {
+		s.Lock()
+		delete(s.conns, ws)
+		s.Unlock()
+		ws.Close()
+	}()
+
+	for _, msg := range s.initial {
+		c.writeMsg(msg)
+	}
+	for {
+		_, p, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req webostv.Msg
+		err = json.Unmarshal(p, &req)
+		if err != nil {
+			continue
+		}
+		ex := s.match(req)
+		if ex == nil {
+			if req.Type != "unsubscribe" {
+				c.writeError(req.Id, "404 no recorded response")
+			}
+			continue
+		}
+		for _, msg := range ex.responses {
+			if msg.Id == ex.req.Id {
+				msg.Id = req.Id
+			}
+			c.writeMsg(msg)
+		}
+	}
+}
@@ -0,0 +1,534 @@
+// Package webostvtest provides a fake LG WebOS TV for testing code which
+// uses the webostv package without a physical TV.
+//
+// The Server speaks the ssap protocol over a local websocket. It issues
+// client keys on registration, simulates the PROMPT pairing, answers
+// requests with scripted handlers, pushes subscription messages and
+// serves the pointer input socket. The ReplayServer answers with the
+// responses found in a capture recorded with webostv.Recorder.
+package webostvtest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/snabb/webostv"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+const (
+	pointerInputSocketUri = "ssap://com.webos.service.networkinput/getPointerInputSocket"
+	pointerSocketPath     = "/pointer"
+	setPinUri             = "ssap://pairing/setPin"
+)
+
+// Handler answers a request to a URI. The returned payload is sent as the
+// response. "returnValue": true is added to the payload if it is missing.
+// If an error is returned, an error message with the error string is sent
+// instead. A Handler may be called concurrently.
+type Handler func(req webostv.Payload) (resp webostv.Payload, err error)
+
+// PointerEvent is a message received on the pointer input socket, for
+// example {"type": "button", "name": "HOME"}.
+type PointerEvent map[string]string
+
+type Server struct {
+	// Address of the fake TV. It can be dialed with the Dialer returned
+	// by Server.Dialer.
+	Address string
+
+	// PairingFunc is called when a client registers without a known
+	// client key. It simulates the user answering the pairing prompt on
+	// the TV. If PairingFunc is nil, the pairing is accepted.
+	PairingFunc func() bool
+
+	// Pin is the PIN which the fake TV "displays" when a client registers
+	// with the PIN pairing type.
+	Pin string
+
+	// UUID is reported in response to "hello" messages.
+	UUID string
+
+	httpServer    *httptest.Server
+	pointerEvents chan PointerEvent
+	done          chan struct{}
+
+	sync.Mutex
+	handlers     map[string]Handler
+	clientKeys   map[string]bool
+	conns        map[*conn]bool
+	pointerConns map[*websocket.Conn]bool
+}
+
+type conn struct {
+	ws         *websocket.Conn
+	writeMutex sync.Mutex
+
+	sync.Mutex
+	registered bool
+	subs       map[string]string // subscription id to uri
+	pinCh      chan string       // receives PINs during PIN pairing
+}
+
+var upgrader = websocket.Upgrader{}
+
+// NewServer starts a fake TV listening on a local port. The caller should
+// call Close when finished.
+func NewServer() *Server {
+	return newServer(false)
+}
+
+// NewTLSServer is like NewServer but the fake TV uses TLS with a self
+// signed certificate, like a real TV does. See Certificate.
+func NewTLSServer() *Server {
+	return newServer(true)
+}
+
+func newServer(useTLS bool) *Server {
+	s := &Server{
+		Pin:           "12345678",
+		UUID:          "ee5b3b1f-a8a0-4b52-9a2b-3fe4a6e4ce1a",
+		pointerEvents: make(chan PointerEvent, 100),
+		done:          make(chan struct{}),
+		handlers:      make(map[string]Handler),
+		clientKeys:    make(map[string]bool),
+		conns:         make(map[*conn]bool),
+		pointerConns:  make(map[*websocket.Conn]bool),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveSsap)
+	mux.HandleFunc(pointerSocketPath, s.servePointer)
+	s.httpServer = httptest.NewUnstartedServer(mux)
+	scheme := "ws://"
+	if useTLS {
+		s.httpServer.StartTLS()
+		scheme = "wss://"
+	} else {
+		s.httpServer.Start()
+	}
+	s.Address = s.httpServer.Listener.Addr().String()
+
+	s.Handle(pointerInputSocketUri, func(webostv.Payload) (webostv.Payload, error) {
+		return webostv.Payload{
+			"socketPath": scheme + s.Address + pointerSocketPath,
+		}, nil
+	})
+	return s
+}
+
+// Dialer returns a Dialer which connects to the Server regardless of the
+// address which is dialed. Use it as dialer.Dial(server.Address).
+func (s *Server) Dialer() *webostv.Dialer {
+	return dialerFor(s.Address, s.httpServer.TLS != nil)
+}
+
+func dialerFor(address string, useTLS bool) *webostv.Dialer {
+	return &webostv.Dialer{
+		DisableTLS: !useTLS,
+		WebsocketDialer: &websocket.Dialer{
+			NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, address)
+			},
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		},
+	}
+}
+
+// Certificate returns the certificate of a Server started with
+// NewTLSServer, or nil.
+func (s *Server) Certificate() *x509.Certificate {
+	if s.httpServer.TLS == nil {
+		return nil
+	}
+	return s.httpServer.Certificate()
+}
+
+// Close disconnects all clients and stops the Server.
+func (s *Server) Close() {
+	close(s.done)
+	s.Lock()
+	for c := range s.conns {
+		c.ws.Close()
+	}
+	for ws := range s.pointerConns {
+		ws.Close()
+	}
+	s.Unlock()
+	s.httpServer.Close()
+}
+
+// DropConnections closes the connections of all clients, including the
+// pointer input sockets, simulating the TV going away. The Server keeps
+// accepting new connections.
+func (s *Server) DropConnections() {
+	s.Lock()
+	for c := range s.conns {
+		c.ws.Close()
+	}
+	s.Unlock()
+	s.DropPointerConnections()
+}
+
+// DropPointerConnections closes all pointer input sockets.
+func (s *Server) DropPointerConnections() {
+	s.Lock()
+	for ws := range s.pointerConns {
+		ws.Close()
+	}
+	s.Unlock()
+}
+
+// Handle sets the Handler for the given URI, replacing any previous one.
+func (s *Server) Handle(uri string, h Handler) {
+	s.Lock()
+	s.handlers[uri] = h
+	s.Unlock()
+}
+
+// HandleResponse sets a Handler for the given URI which always responds
+// with resp.
+func (s *Server) HandleResponse(uri string, resp webostv.Payload) {
+	s.Handle(uri, func(webostv.Payload) (webostv.Payload, error) {
+		return resp, nil
+	})
+}
+
+// AddClientKey makes the Server accept the given client key without
+// pairing.
+func (s *Server) AddClientKey(key string) {
+	s.Lock()
+	s.clientKeys[key] = true
+	s.Unlock()
+}
+
+// RevokeClientKeys makes the Server forget all client keys, so that the
+// clients must pair again.
+func (s *Server) RevokeClientKeys() {
+	s.Lock()
+	s.clientKeys = make(map[string]bool)
+	s.Unlock()
+}
+
+// Publish sends payload to all subscribers of uri and returns the number
+// of subscriptions the message was sent to.
+func (s *Server) Publish(uri string, payload webostv.Payload) (n int) {
+	s.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.Unlock()
+
+	for _, c := range conns {
+		c.Lock()
+		var ids []string
+		for id, subUri := range c.subs {
+			if subUri == uri {
+				ids = append(ids, id)
+			}
+		}
+		c.Unlock()
+		for _, id := range ids {
+			if c.writeResponse(id, payload) == nil {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// Send sends msg to all connected clients as is. It can be used for
+// sending messages which are not responses to any request.
+func (s *Server) Send(msg webostv.Msg) {
+	s.Lock()
+	conns := make([]*conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.Unlock()
+
+	for _, c := range conns {
+		c.writeMsg(msg)
+	}
+}
+
+// Subscribers returns the number of active subscriptions to uri.
+func (s *Server) Subscribers(uri string) (n int) {
+	s.Lock()
+	defer s.Unlock()
+	for c := range s.conns {
+		c.Lock()
+		for _, subUri := range c.subs {
+			if subUri == uri {
+				n++
+			}
+		}
+		c.Unlock()
+	}
+	return n
+}
+
+// PointerEvents returns a channel which receives the messages sent to the
+// pointer input socket.
+func (s *Server) PointerEvents() <-chan PointerEvent {
+	return s.pointerEvents
+}
+
+func (s *Server) serveSsap(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &conn{
+		ws:   ws,
+		subs: make(map[string]string),
+	}
+	s.Lock()
+	s.conns[c] = true
+	s.Unlock()
+
+	defer func() {
+		s.Lock()
+		delete(s.conns, c)
+		s.Unlock()
+		ws.Close()
+	}()
+
+	for {
+		_, p, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg webostv.Msg
+		err = json.Unmarshal(p, &msg)
+		if err != nil {
+			continue
+		}
+		switch msg.Type {
+		case "hello":
+			c.writeMsg(webostv.Msg{
+				Type: "hello",
+				Id:   msg.Id,
+				Payload: webostv.Payload{
+					"protocolVersion":        1,
+					"deviceType":             "tv",
+					"deviceOS":               "webOS",
+					"deviceOSVersion":        "4.1.0",
+					"deviceOSReleaseVersion": "3.0",
+					"deviceUUID":             s.UUID,
+					"pairingTypes":           []string{"PIN", "PROMPT", "COMBINED"},
+				},
+			})
+		case "register":
+			go s.register(c, msg)
+		case "request", "subscribe":
+			go s.request(c, msg)
+		case "unsubscribe":
+			c.Lock()
+			delete(c.subs, msg.Id)
+			c.Unlock()
+		}
+	}
+}
+
+func (s *Server) register(c *conn, msg webostv.Msg) {
+	key, _ := msg.Payload["client-key"].(string)
+	s.Lock()
+	known := s.clientKeys[key]
+	s.Unlock()
+
+	if !known {
+		pairingType, _ := msg.Payload["pairingType"].(string)
+		if pairingType != webostv.PairingTypePin {
+			pairingType = webostv.PairingTypePrompt
+		}
+		pinCh := make(chan string, 1)
+		if pairingType == webostv.PairingTypePin {
+			c.Lock()
+			c.pinCh = pinCh
+			c.Unlock()
+		}
+		c.writeMsg(webostv.Msg{
+			Type: "response",
+			Id:   msg.Id,
+			Payload: webostv.Payload{
+				"pairingType": pairingType,
+				"returnValue": true,
+			},
+		})
+		if pairingType == webostv.PairingTypePin {
+			select {
+			case <-pinCh:
+			case <-s.done:
+				return
+			}
+		} else if s.PairingFunc != nil && !s.PairingFunc() {
+			c.writeError(msg.Id, "403 User denied access")
+			return
+		}
+		key = randSeq(32)
+		s.AddClientKey(key)
+	}
+
+	c.Lock()
+	c.registered = true
+	c.Unlock()
+	c.writeMsg(webostv.Msg{
+		Type:    "registered",
+		Id:      msg.Id,
+		Payload: webostv.Payload{"client-key": key},
+	})
+}
+
+func (s *Server) request(c *conn, msg webostv.Msg) {
+	if msg.Uri == setPinUri {
+		s.setPin(c, msg)
+		return
+	}
+	c.Lock()
+	registered := c.registered
+	c.Unlock()
+	if !registered {
+		c.writeError(msg.Id, "401 insufficient permissions")
+		return
+	}
+
+	s.Lock()
+	h := s.handlers[msg.Uri]
+	s.Unlock()
+	if h == nil {
+		c.writeError(msg.Id, "404 no such service or method")
+		return
+	}
+	resp, err := h(msg.Payload)
+	if err != nil {
+		c.writeError(msg.Id, err.Error())
+		return
+	}
+	if msg.Type == "subscribe" {
+		c.Lock()
+		c.subs[msg.Id] = msg.Uri
+		c.Unlock()
+		tmp := webostv.Payload{"subscribed": true}
+		for k, v := range resp {
+			tmp[k] = v
+		}
+		resp = tmp
+	}
+	c.writeResponse(msg.Id, resp)
+}
+
+func (s *Server) setPin(c *conn, msg webostv.Msg) {
+	pin, _ := msg.Payload["pin"].(string)
+	c.Lock()
+	pinCh := c.pinCh
+	if pinCh != nil && pin == s.Pin {
+		c.pinCh = nil
+	}
+	c.Unlock()
+
+	if pinCh == nil {
+		c.writeError(msg.Id, "500 Application error")
+		return
+	}
+	if pin != s.Pin {
+		c.writeResponse(msg.Id, webostv.Payload{
+			"returnValue": false,
+			"errorCode":   -1,
+			"errorText":   "wrong PIN",
+		})
+		return
+	}
+	c.writeResponse(msg.Id, nil)
+	pinCh <- pin
+}
+
+func (s *Server) servePointer(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	s.Lock()
+	s.pointerConns[ws] = true
+	s.Unlock()
+	defer func() {
+		s.Lock()
+		delete(s.pointerConns, ws)
+		s.Unlock()
+	}()
+
+	for {
+		_, p, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+		ev := make(PointerEvent)
+		for _, line := range strings.Split(string(p), "\n") {
+			if i := strings.Index(line, ":"); i >= 0 {
+				ev[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+			}
+		}
+		select {
+		case s.pointerEvents <- ev:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (c *conn) writeMsg(msg webostv.Msg) error {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "JSON marshal error")
+	}
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	return c.ws.WriteMessage(websocket.TextMessage, buf)
+}
+
+func (c *conn) writeResponse(id string, payload webostv.Payload) error {
+	p := make(webostv.Payload)
+	for k, v := range payload {
+		p[k] = v
+	}
+	if _, ok := p["returnValue"]; !ok {
+		p["returnValue"] = true
+	}
+	return c.writeMsg(webostv.Msg{
+		Type:    "response",
+		Id:      id,
+		Payload: p,
+	})
+}
+
+func (c *conn) writeError(id string, errStr string) error {
+	return c.writeMsg(webostv.Msg{
+		Type:    "error",
+		Id:      id,
+		Error:   errStr,
+		Payload: webostv.Payload{},
+	})
+}
+
+var randSeqLetters = []rune("abcdefghijklmnopqrstuvwxyz0123456789")
+
+func randSeq(n int) string {
+	b := make([]rune, n)
+	for i := range b {
+		b[i] = randSeqLetters[rand.Intn(len(randSeqLetters))]
+	}
+	return string(b)
+}
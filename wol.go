@@ -0,0 +1,121 @@
+package webostv
+
+import (
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"net"
+	"time"
+)
+
+// ErrUnknownMAC is returned by PowerOn if the MAC address is not given
+// and it is not known from a previous registration.
+var ErrUnknownMAC = errors.New("MAC address of the TV is not known")
+
+const DefaultBroadcastAddress = "255.255.255.255:9"
+
+var PowerOnPollInterval = time.Second * 2
+
+// WakeOnLAN sends a Wake-on-LAN magic packet to the given MAC address. The
+// MAC address of the TV is available in CurrentSWInformation.DeviceId.
+// If broadcastAddr is empty, DefaultBroadcastAddress is used. If the port
+// is not specified, port 9 is used.
+func WakeOnLAN(mac, broadcastAddr string) (err error) {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return errors.Wrap(err, "invalid MAC address")
+	}
+	if len(hwAddr) != 6 {
+		return errors.Errorf("invalid MAC address length: %s", mac)
+	}
+
+	if broadcastAddr == "" {
+		broadcastAddr = DefaultBroadcastAddress
+	} else if _, _, err := net.SplitHostPort(broadcastAddr); err != nil {
+		broadcastAddr = net.JoinHostPort(broadcastAddr, "9")
+	}
+
+	conn, err := net.Dial("udp", broadcastAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(magicPacket(hwAddr))
+	return err
+}
+
+func magicPacket(hwAddr net.HardwareAddr) []byte {
+	var buf bytes.Buffer
+	buf.Write(bytes.Repeat([]byte{0xff}, 6))
+	for i := 0; i < 16; i++ {
+		buf.Write(hwAddr)
+	}
+	return buf.Bytes()
+}
+
+// PowerOn turns on the TV at address by sending Wake-on-LAN packets to mac
+// until the TV accepts a connection and registration with the given client
+// key succeeds, or until ctx is done. If key is empty, the registration is
+// not attempted. If mac is empty, the MAC address remembered in the
+// KeyStore of the dialer is used, see KnownMAC. The connection used for
+// polling is closed before returning; the caller dials again to control
+// the TV.
+func (dialer *Dialer) PowerOn(ctx context.Context, address, mac, broadcastAddr, key string) (err error) {
+	if mac == "" {
+		mac, err = dialer.KnownMAC(address)
+		if err != nil {
+			return err
+		}
+		if mac == "" {
+			return ErrUnknownMAC
+		}
+	}
+	for {
+		err = WakeOnLAN(mac, broadcastAddr)
+		if err != nil {
+			return err
+		}
+		err = dialer.probe(ctx, address, key)
+		if err == nil || IsCertificateMismatch(err) {
+			return err
+		}
+		select {
+		case <-time.After(PowerOnPollInterval):
+		case <-ctx.Done():
+			return contextError(ctx)
+		}
+	}
+	// not reached
+}
+
+// KnownMAC returns the MAC address of the TV at address saved in the
+// KeyStore of the dialer when registering. An empty string is returned
+// if it is not known or the KeyStore does not implement AddressLookup.
+func (dialer *Dialer) KnownMAC(address string) (mac string, err error) {
+	lookup, ok := dialer.KeyStore.(AddressLookup)
+	if !ok {
+		return "", nil
+	}
+	_, cred, err := lookup.LookupAddress(address)
+	return cred.MAC, err
+}
+
+// probe checks if the ssap service of the TV is reachable.
+func (dialer *Dialer) probe(ctx context.Context, address, key string) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	tv, err := dialer.DialContext(ctx, address)
+	if err != nil {
+		return err
+	}
+	defer tv.Close()
+	if key == "" {
+		return nil
+	}
+	go tv.MessageHandler()
+
+	_, err = tv.RegisterContext(ctx, key)
+	return err
+}
@@ -0,0 +1,41 @@
+package webostv_test
+
+import (
+	"bytes"
+	"github.com/snabb/webostv"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWakeOnLAN(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = webostv.WakeOnLAN("3c:cd:93:7b:91:9e", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	buf := make([]byte, 200)
+	n, _, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := bytes.Repeat([]byte{0xff}, 6)
+	for i := 0; i < 16; i++ {
+		expected = append(expected, 0x3c, 0xcd, 0x93, 0x7b, 0x91, 0x9e)
+	}
+	if !bytes.Equal(buf[:n], expected) {
+		t.Errorf("unexpected magic packet % x", buf[:n])
+	}
+
+	for _, mac := range []string{"", "3c:cd:93:7b:91", "3c:cd:93:7b:91:zz", "00:00:00:00:fe:80:00:00"} {
+		if err = webostv.WakeOnLAN(mac, conn.LocalAddr().String()); err == nil {
+			t.Errorf("%q: expected error", mac)
+		}
+	}
+}